@@ -0,0 +1,57 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import "context"
+
+// Ancestor represents a node that encloses the node being visited.
+type Ancestor struct {
+	// Path is the walker path of the node, it is empty for the
+	// document itself.
+	Path []string
+	Node any
+}
+
+// Ancestors returns the nodes that enclose the node currently being
+// visited, starting with the document and ending with the node's parent,
+// ie. the parent argument passed to the Visitor. For example, the
+// ancestors of a response's schema are the *openapi3.T, *openapi3.Paths,
+// *openapi3.PathItem, *openapi3.Operation, *openapi3.Responses,
+// *openapi3.ResponseRef, *openapi3.Content and *openapi3.MediaType.
+// It must be called with the context passed to a ContextVisitor.
+func Ancestors(ctx context.Context) []Ancestor {
+	st := walkStateFromContext(ctx)
+	if st == nil {
+		return nil
+	}
+	return copyAncestors(st.ancestors)
+}
+
+// NearestAncestor returns the innermost ancestor of the node currently
+// being visited that is of type T, along with its path. For example,
+// NearestAncestor[*openapi3.Operation](ctx) returns the operation, if
+// any, that contains the node. It must be called with the context passed
+// to a ContextVisitor.
+func NearestAncestor[T any](ctx context.Context) (node T, path []string, ok bool) {
+	st := walkStateFromContext(ctx)
+	if st == nil {
+		return
+	}
+	for i := len(st.ancestors) - 1; i >= 0; i-- {
+		if node, ok = st.ancestors[i].Node.(T); ok {
+			path = append([]string{}, st.ancestors[i].Path...)
+			return
+		}
+	}
+	return
+}
+
+func copyAncestors(ancestors []Ancestor) []Ancestor {
+	r := make([]Ancestor, len(ancestors))
+	for i, a := range ancestors {
+		r[i] = Ancestor{Path: append([]string{}, a.Path...), Node: a.Node}
+	}
+	return r
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestAncestors(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	parents := map[string]string{}
+	var responseSchemas []string
+	wk := openapi.NewContextWalker(func(ctx context.Context, path []string, parent, node any) (bool, error) {
+		p := strings.Join(path, ":")
+		ancestors := openapi.Ancestors(ctx)
+		if got, want := ancestors[0].Node, any(doc); got != want {
+			t.Errorf("%v: got %T, want %T", p, got, want)
+		}
+		last := ancestors[len(ancestors)-1]
+		if got, want := last.Node, parent; got != want {
+			t.Errorf("%v: got %T, want %T", p, got, want)
+		}
+		if len(path) > 0 && !strings.HasPrefix(p, strings.Join(last.Path, ":")) {
+			t.Errorf("%v: ancestor path %v is not a prefix", p, last.Path)
+		}
+		if _, ok := parents[p]; !ok {
+			// The first node visited for a path, headers share their
+			// path with their parameter.
+			parents[p] = fmt.Sprintf("%T", parent)
+		}
+		if _, ok := node.(*openapi3.SchemaRef); !ok {
+			return true, nil
+		}
+		_, opPath, ok := openapi.NearestAncestor[*openapi3.Operation](ctx)
+		if !ok || opPath[len(opPath)-1] != "get" {
+			return true, nil
+		}
+		if _, _, ok := openapi.NearestAncestor[*openapi3.ResponseRef](ctx); ok {
+			responseSchemas = append(responseSchemas, p)
+		}
+		return true, nil
+	}, openapi.WalkerOrder(openapi.SortedOrder))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path, parent string
+	}{
+		{"info", "*openapi3.T"},
+		{"paths", "*openapi3.T"},
+		{"paths:/pets/{id}", "*openapi3.Paths"},
+		{"paths:/pets/{id}:get", "*openapi3.PathItem"},
+		{"paths:/pets/{id}:get:parameters", "*openapi3.Operation"},
+		{"paths:/pets/{id}:get:parameters:verbose", "*openapi3.Parameters"},
+		{"paths:/pets/{id}:get:responses:200", "*openapi3.Responses"},
+		{"paths:/pets/{id}:get:responses:200:headers:X-Rate-Limit", "*openapi3.Headers"},
+		{"paths:/pets/{id}:get:responses:200:content:application/json:schema", "*openapi3.MediaType"},
+		{"components:parameters:Limit", "*openapi3.ParametersMap"},
+		{"components:headers:RateLimit", "*openapi3.Headers"},
+		{"components:schemas:Pet:properties:name", "*openapi3.Schemas"},
+		{"components:schemas:Pet:properties", "*openapi3.SchemaRef"},
+		{"components:schemas:Cat:allOf:0", "*openapi3.SchemaRefs"},
+		{"components:securitySchemes:oauth:flows:implicit", "*openapi3.OAuthFlows"},
+	} {
+		if got, want := parents[tc.path], tc.parent; got != want {
+			t.Errorf("%v: got %v, want %v", tc.path, got, want)
+		}
+	}
+
+	if got, want := responseSchemas, []string{
+		"paths:/pets/{id}:get:responses:200:headers:X-Rate-Limit:schema",
+		"paths:/pets/{id}:get:responses:200:content:application/json:schema",
+		"paths:/pets/{id}:get:responses:default:content:application/json:schema",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
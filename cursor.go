@@ -0,0 +1,429 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ApplyFunc is called by Apply for every node in a document. It follows
+// the same conventions as Visitor, but is passed a Cursor rather than
+// the path, parent and node.
+type ApplyFunc func(c *Cursor) (ok bool, err error)
+
+// Apply walks doc, calling pre for every node before its children are
+// visited and post, if not nil, afterwards. The Cursor passed to pre and
+// post may be used to replace or delete the current node, or to insert
+// new nodes alongside it. The children of a node that is replaced or
+// deleted by pre are not visited, nor are any inserted nodes. Apply
+// accepts the same options as NewWalker, except that WalkerParallel is
+// ignored since nodes may be deleted from, or inserted into, their
+// containers.
+func Apply(ctx context.Context, doc *openapi3.T, pre, post ApplyFunc, opts ...WalkerOption) error {
+	w := NewContextWalker(applyVisitor(pre), opts...).(*nodeWalker)
+	w.postVisitor = applyVisitor(post)
+	w.opts.parallel = 0
+	w.store31 = true
+	return w.WalkContext(ctx, doc)
+}
+
+// ApplyV2 is like Apply, but for swagger 2.0 documents.
+func ApplyV2(ctx context.Context, doc *openapi2.T, pre, post ApplyFunc, opts ...WalkerOption) error {
+	w := NewV2ContextWalker(applyVisitor(pre), opts...).(v2Walker)
+	w.postVisitor = applyVisitor(post)
+	w.opts.parallel = 0
+	return w.WalkContext(ctx, doc)
+}
+
+func applyVisitor(fn ApplyFunc) ContextVisitor {
+	if fn == nil {
+		return nil
+	}
+	return func(ctx context.Context, path []string, parent, node any) (bool, error) {
+		c := newCursor(walkStateFromContext(ctx), path, node)
+		ok, err := fn(c)
+		if ok && err == nil && c.modified {
+			return true, SkipNode
+		}
+		return ok, err
+	}
+}
+
+// Cursor describes a node encountered by Apply and provides methods for
+// modifying the document in place. A Cursor is only valid for the
+// duration of the ApplyFunc call that it is passed to.
+type Cursor struct {
+	path     []string
+	state    *walkState
+	node     any
+	parent   any
+	slot     *slot
+	modified bool
+}
+
+func newCursor(st *walkState, path []string, node any) *Cursor {
+	c := &Cursor{path: path, node: node, state: st}
+	if st == nil || len(path) == 0 {
+		return c
+	}
+	key := path[len(path)-1]
+	// Containers that are passed by value, rather than by reference,
+	// cannot be modified and are skipped in favour of their own container.
+	for i := len(st.ancestors) - 1; i >= 0; i-- {
+		container := st.ancestors[i].Node
+		switch reflect.ValueOf(container).Kind() {
+		case reflect.Pointer, reflect.Map:
+		default:
+			continue
+		}
+		c.parent = container
+		c.slot = locate(container, key, node)
+		break
+	}
+	return c
+}
+
+// Path returns the path of the current node.
+func (c *Cursor) Path() []string {
+	return c.path
+}
+
+// Name returns the last element of the current node's path.
+func (c *Cursor) Name() string {
+	if len(c.path) == 0 {
+		return ""
+	}
+	return c.path[len(c.path)-1]
+}
+
+// RefChain returns the $refs followed to reach the current node,
+// see RefChain.
+func (c *Cursor) RefChain() []Ref {
+	if c.state == nil {
+		return nil
+	}
+	return append([]Ref{}, c.state.refs...)
+}
+
+// Ancestors returns the ancestors of the current node, see Ancestors.
+func (c *Cursor) Ancestors() []Ancestor {
+	if c.state == nil {
+		return nil
+	}
+	return copyAncestors(c.state.ancestors)
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() any {
+	return c.node
+}
+
+// Parent returns the node that contains the current node, for example
+// the *openapi3.Schemas map for a property or the *openapi3.Operation
+// for its responses.
+func (c *Cursor) Parent() any {
+	return c.parent
+}
+
+// Index returns the index of the current node if it is an element of
+// a slice, and -1 otherwise.
+func (c *Cursor) Index() int {
+	if c.slot == nil || c.slot.kind != sliceSlot {
+		return -1
+	}
+	return c.slot.index
+}
+
+// Replace replaces the current node with n, which must be of a type
+// that can be stored where the current node is.
+func (c *Cursor) Replace(n any) error {
+	s, err := c.modifiable()
+	if err != nil {
+		return err
+	}
+	if err := s.set(n); err != nil {
+		return c.errorf("%v", err)
+	}
+	c.node, c.modified = n, true
+	return nil
+}
+
+// Delete deletes the current node from its parent. Fields of structs are
+// set to their zero value, map entries are deleted and slice elements are
+// removed.
+func (c *Cursor) Delete() error {
+	s, err := c.modifiable()
+	if err != nil {
+		return err
+	}
+	s.delete()
+	c.slot, c.modified = nil, true
+	return nil
+}
+
+// InsertBefore inserts n before the current node, which must be an
+// element of a slice.
+func (c *Cursor) InsertBefore(n any) error {
+	return c.insert(n, 0)
+}
+
+// InsertAfter inserts n after the current node, which must be an
+// element of a slice.
+func (c *Cursor) InsertAfter(n any) error {
+	return c.insert(n, 1)
+}
+
+func (c *Cursor) insert(n any, offset int) error {
+	s, err := c.modifiable()
+	if err != nil {
+		return err
+	}
+	if s.kind != sliceSlot {
+		return c.errorf("not an element of a slice")
+	}
+	if err := s.insert(n, s.index+offset); err != nil {
+		return c.errorf("%v", err)
+	}
+	if offset == 0 {
+		s.index++
+	}
+	return nil
+}
+
+func (c *Cursor) modifiable() (*slot, error) {
+	if c.slot == nil {
+		if c.modified {
+			return nil, c.errorf("node has been deleted")
+		}
+		return nil, c.errorf("node cannot be modified")
+	}
+	return c.slot, nil
+}
+
+func (c *Cursor) errorf(format string, args ...any) error {
+	path := strings.Join(c.path, ":")
+	if c.state != nil {
+		path = c.state.formatPath(c.path, c.node)
+	}
+	return fmt.Errorf("%v: %v", path, fmt.Sprintf(format, args...))
+}
+
+type slotKind int
+
+const (
+	fieldSlot slotKind = iota
+	mapSlot
+	sliceSlot
+)
+
+// slot represents the location of a node within its container.
+type slot struct {
+	kind slotKind
+	// v is the field, map or slice that contains the node.
+	v reflect.Value
+	// key is the key of the node in a map.
+	key reflect.Value
+	// index is the index of the node in a slice.
+	index int
+	// indirect is true if the node is a pointer to the field rather than
+	// the value of the field, eg. the &op.Parameters passed to visitors.
+	indirect bool
+}
+
+func (s *slot) value(n any) (reflect.Value, error) {
+	var typ reflect.Type
+	switch s.kind {
+	case fieldSlot:
+		typ = s.v.Type()
+		if s.indirect {
+			typ = reflect.PointerTo(typ)
+		}
+	default:
+		typ = s.v.Type().Elem()
+	}
+	nv := reflect.ValueOf(n)
+	if !nv.IsValid() {
+		return reflect.Zero(typ), nil
+	}
+	if !nv.Type().AssignableTo(typ) {
+		return reflect.Value{}, fmt.Errorf("%v is not assignable to %v", nv.Type(), typ)
+	}
+	if s.indirect {
+		if nv.IsNil() {
+			return reflect.Zero(s.v.Type()), nil
+		}
+		return nv.Elem(), nil
+	}
+	return nv, nil
+}
+
+func (s *slot) set(n any) error {
+	nv, err := s.value(n)
+	if err != nil {
+		return err
+	}
+	switch s.kind {
+	case fieldSlot:
+		s.v.Set(nv)
+	case mapSlot:
+		s.v.SetMapIndex(s.key, nv)
+	case sliceSlot:
+		s.v.Index(s.index).Set(nv)
+	}
+	return nil
+}
+
+// delete and insert always allocate a new slice so that a walk that
+// is ranging over the original slice is unaffected.
+func (s *slot) delete() {
+	switch s.kind {
+	case fieldSlot:
+		s.v.Set(reflect.Zero(s.v.Type()))
+	case mapSlot:
+		s.v.SetMapIndex(s.key, reflect.Value{})
+	case sliceSlot:
+		ns := reflect.MakeSlice(s.v.Type(), 0, s.v.Len()-1)
+		ns = reflect.AppendSlice(ns, s.v.Slice(0, s.index))
+		ns = reflect.AppendSlice(ns, s.v.Slice(s.index+1, s.v.Len()))
+		s.v.Set(ns)
+	}
+}
+
+func (s *slot) insert(n any, at int) error {
+	nv, err := s.value(n)
+	if err != nil {
+		return err
+	}
+	ns := reflect.MakeSlice(s.v.Type(), 0, s.v.Len()+1)
+	ns = reflect.AppendSlice(ns, s.v.Slice(0, at))
+	ns = reflect.Append(ns, nv)
+	ns = reflect.AppendSlice(ns, s.v.Slice(at, s.v.Len()))
+	s.v.Set(ns)
+	return nil
+}
+
+// locate returns the location of node, whose path ends in key, within
+// container. For $ref types such as *openapi3.SchemaRef both the
+// ref and its Value are searched.
+func locate(container any, key string, node any) *slot {
+	v := reflect.ValueOf(container)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	candidates := []reflect.Value{v}
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName("Value"); f.IsValid() && f.Kind() == reflect.Pointer && !f.IsNil() {
+			candidates = append(candidates, f.Elem())
+		}
+	}
+	nv := reflect.ValueOf(node)
+	for _, cv := range candidates {
+		if s := locateIn(cv, key, nv); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func locateIn(v reflect.Value, key string, nv reflect.Value) *slot {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		k := reflect.ValueOf(key).Convert(v.Type().Key())
+		if v.MapIndex(k).IsValid() {
+			return &slot{kind: mapSlot, v: v, key: k}
+		}
+	case reflect.Slice:
+		if !v.CanSet() {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if sameNode(v.Index(i), nv) {
+				return &slot{kind: sliceSlot, v: v, index: i}
+			}
+		}
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < v.Len() {
+			return &slot{kind: sliceSlot, v: v, index: i}
+		}
+	case reflect.Struct:
+		if !v.CanAddr() {
+			return nil
+		}
+		if s := locateField(v, nv); s != nil {
+			return s
+		}
+		return locateByName(v, key, nv)
+	}
+	return nil
+}
+
+// locateField finds the field of v, or of any struct valued field of v,
+// that is, or whose address is, nv.
+func locateField(v reflect.Value, nv reflect.Value) *slot {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		if sameNode(f.Addr(), nv) {
+			return &slot{kind: fieldSlot, v: f, indirect: true}
+		}
+		if sameNode(f, nv) {
+			return &slot{kind: fieldSlot, v: f}
+		}
+		if f.Kind() == reflect.Struct {
+			if s := locateField(f, nv); s != nil {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// locateByName finds the field of v whose json name is key, or failing
+// that, a map valued field of v that contains key. It is used for nodes,
+// such as maps and strings, that cannot be compared by identity.
+func locateByName(v reflect.Value, key string, nv reflect.Value) *slot {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == key && nv.IsValid() && f.Type() == nv.Type() {
+			return &slot{kind: fieldSlot, v: f}
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.CanSet() && f.Kind() == reflect.Map {
+			if s := locateIn(f, key, nv); s != nil {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// sameNode returns true if v and nv are the same pointer.
+func sameNode(v, nv reflect.Value) bool {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Pointer && nv.Kind() == reflect.Pointer &&
+		v.Type() == nv.Type() && v.Pointer() == nv.Pointer()
+}
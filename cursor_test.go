@@ -0,0 +1,165 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	doc := loadYAML("kitchensink.yaml")
+	idSchema := openapi3.NewStringSchema().NewRef()
+	visited := map[string]bool{}
+	var postVisited []string
+	pre := func(c *openapi.Cursor) (bool, error) {
+		path := strings.Join(c.Path(), ":")
+		visited[path] = true
+		switch path {
+		case "components:schemas:Pet:properties:owner":
+			if _, ok := c.Parent().(*openapi3.Schemas); !ok {
+				t.Errorf("unexpected parent: %T", c.Parent())
+			}
+			return true, c.Delete()
+		case "components:schemas:Id":
+			if err := c.Replace("not a schema"); err == nil {
+				t.Errorf("expected an error")
+			}
+			return true, c.Replace(idSchema)
+		case "paths:/pets/{id}:get:parameters:limit":
+			if got, want := c.Index(), 0; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			return true, c.Delete()
+		case "paths:/pets/{id}:get:servers:0":
+			if err := c.InsertBefore(&openapi3.Server{URL: "https://before.example.com"}); err != nil {
+				return false, err
+			}
+			if got, want := c.Index(), 1; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			return true, c.InsertAfter(&openapi3.Server{URL: "https://after.example.com"})
+		case "paths:/pets/{id}:get:externalDocs":
+			if err := c.InsertAfter(&openapi3.ExternalDocs{}); err == nil {
+				t.Errorf("expected an error")
+			}
+			return true, c.Delete()
+		case "components:securitySchemes:oauth:flows":
+			return true, c.Delete()
+		}
+		return true, nil
+	}
+	post := func(c *openapi.Cursor) (bool, error) {
+		if c.Name() == "requestBody" {
+			postVisited = append(postVisited, strings.Join(c.Path(), ":"))
+		}
+		return true, nil
+	}
+	if err := openapi.Apply(ctx, doc, pre, post, openapi.WalkerOrder(openapi.SortedOrder)); err != nil {
+		t.Fatal(err)
+	}
+
+	props := doc.Components.Schemas["Pet"].Value.Properties
+	if _, ok := props["owner"]; ok {
+		t.Errorf("owner was not deleted")
+	}
+	if got, want := len(props), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := doc.Components.Schemas["Id"], idSchema; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	get := doc.Paths["/pets/{id}"].Get
+	if got, want := len(get.Parameters), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !visited["paths:/pets/{id}:get:parameters:verbose"] {
+		t.Errorf("parameter following a deleted one was not visited")
+	}
+	if visited["components:schemas:Pet:properties:owner:not"] || visited["components:schemas:Id:anyOf"] {
+		t.Errorf("children of a deleted or replaced node were visited")
+	}
+	var urls []string
+	for _, s := range *get.Servers {
+		urls = append(urls, s.URL)
+	}
+	if got, want := urls, []string{"https://before.example.com", "https://pets.example.com", "https://after.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if get.ExternalDocs != nil {
+		t.Errorf("externalDocs was not deleted")
+	}
+	if doc.Components.SecuritySchemes["oauth"].Value.Flows != nil {
+		t.Errorf("flows were not deleted")
+	}
+	if got, want := postVisited, []string{
+		"paths:/owners:post:requestBody",
+		"paths:/pets/{id}:put:requestBody",
+		"paths:/pets/{id}:put:callbacks:onUpdate:{$request.body#/callbackUrl}:post:requestBody",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Errors are reported as JSON Pointers if requested.
+	for _, tc := range []struct {
+		opts []openapi.WalkerOption
+		msg  string
+	}{
+		{nil, "paths:/pets/{id}:get:parameters:limit:schema: not an element of a slice"},
+		{[]openapi.WalkerOption{openapi.WalkerJSONPointers(true)}, "/components/parameters/Limit/schema: not an element of a slice"},
+	} {
+		var msg string
+		err := openapi.Apply(ctx, loadYAML("kitchensink.yaml"), func(c *openapi.Cursor) (bool, error) {
+			path := c.Path()
+			if _, ok := c.Node().(*openapi3.SchemaRef); ok && len(msg) == 0 && len(path) > 4 && path[1] == "/pets/{id}" && path[3] == "parameters" {
+				msg = c.InsertAfter(openapi3.NewStringSchema().NewRef()).Error()
+			}
+			return true, nil
+		}, nil, append(tc.opts, openapi.WalkerOrder(openapi.SortedOrder))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := msg, tc.msg; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestApplyV2(t *testing.T) {
+	doc := loadV2("v2kitchensink.json")
+	err := openapi.ApplyV2(context.Background(), doc, func(c *openapi.Cursor) (bool, error) {
+		switch c.Node().(type) {
+		case *openapi2.Response:
+			if c.Name() == "default" {
+				return true, c.Replace(&openapi2.Response{Description: "replaced"})
+			}
+		case *openapi2.Parameter:
+			if c.Name() == "tags" {
+				return true, c.Delete()
+			}
+		}
+		return true, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := doc.Paths["/pets/{id}"].Get
+	if got, want := get.Responses["default"].Description, "replaced"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var names []string
+	for _, p := range get.Parameters {
+		names = append(names, p.Ref)
+	}
+	if got, want := names, []string{"#/parameters/Limit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,477 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// Encoder writes documents to an io.Writer as YAML or JSON, formatted
+// exactly as per FormatV3 and FormatV2. A document is encoded field by
+// field: its paths and components, or for 2.0 documents its paths,
+// definitions, parameters and responses, are written one entry at a time
+// with each entry being marshaled to JSON, by its MarshalJSON method,
+// only when it is written. That JSON is formatted as it is scanned rather
+// than being decoded into a generic map or a tree of yaml.Nodes, so that
+// the memory required is proportional to the largest such entry rather
+// than to the entire document. YAML is written one mapping entry at a
+// time, with only runs of consecutive entries whose values are not
+// themselves non-empty mappings, eg. scalars and sequences, being held as
+// yaml.Nodes in order to be encoded by gopkg.in/yaml.v3. FlowArrays and
+// LineWidth are applied to each run in turn. FormatSource is the
+// exception since the entire document must be decoded in order to compare
+// it with the source, although the patched source is still written
+// directly to the writer.
+type Encoder struct {
+	w      io.Writer
+	isYAML bool
+	opts   []FormatOption
+}
+
+// NewEncoder returns an Encoder that writes to w as YAML or JSON using
+// the supplied options.
+func NewEncoder(w io.Writer, isYAML bool, opts ...FormatOption) *Encoder {
+	return &Encoder{w: w, isYAML: isYAML, opts: opts}
+}
+
+// EncodeV3 writes doc to the Encoder's writer.
+func (e *Encoder) EncodeV3(doc *openapi3.T) error {
+	return e.encode(doc, v3Format)
+}
+
+// EncodeV2 writes doc to the Encoder's writer.
+func (e *Encoder) EncodeV2(doc *openapi2.T) error {
+	return e.encode(doc, v2Format)
+}
+
+func (e *Encoder) encode(doc any, dialect formatDialect) error {
+	var o formatOptions
+	for _, fn := range e.opts {
+		fn(&o)
+	}
+	if o.err != nil {
+		return o.err
+	}
+	var order *keyOrder
+	if !o.sortKeys {
+		order = dialect.keyOrders(o.keyOrders)
+	}
+	if e.isYAML && o.source != nil {
+		tmp, err := decodeJSON(doc)
+		if err != nil {
+			return err
+		}
+		ok, err := patchSource(e.w, o.source, dialect.newDoc(), tmp, order, o.style)
+		if ok || err != nil {
+			return err
+		}
+	}
+	v, err := value(doc)
+	if err != nil {
+		return err
+	}
+	return o.style.write(e.w, v, order, e.isYAML)
+}
+
+// jsonValue is a value to be written, either rawJSON or a lazyObject.
+type jsonValue interface {
+	kind() byte
+	isMapping() bool
+	// object returns the entries of an object, as accepted by value.
+	object() (map[string]any, error)
+	// node returns the yaml.Node for the value, as per orderedNode.
+	node(ko *keyOrder) (*yaml.Node, error)
+}
+
+// value returns the jsonValue for v. Documents, and the containers within
+// them that may be large, are returned as lazyObjects and all other Go
+// values are marshaled to JSON. The conditions under which the fields of
+// a lazyObject are present are those used by the MarshalJSON methods of
+// openapi3.T, openapi3.Components and openapi2.T.
+func value(v any) (jsonValue, error) {
+	var (
+		shallow any
+		fields  map[string]any
+	)
+	switch x := v.(type) {
+	case rawJSON:
+		return x, nil
+	case lazyObject:
+		return x, nil
+	case *openapi3.T:
+		c := *x
+		c.Components, c.Paths = nil, nil
+		shallow, fields = c, map[string]any{}
+		if x.Components != nil {
+			fields["components"] = x.Components
+		}
+		if x.Paths != nil {
+			fields["paths"] = lazyMap(x.Paths)
+		}
+	case *openapi3.Components:
+		shallow = openapi3.Components{Extensions: x.Extensions}
+		fields = nonEmpty(map[string]any{
+			"schemas":         x.Schemas,
+			"parameters":      x.Parameters,
+			"headers":         x.Headers,
+			"requestBodies":   x.RequestBodies,
+			"responses":       x.Responses,
+			"securitySchemes": x.SecuritySchemes,
+			"examples":        x.Examples,
+			"links":           x.Links,
+			"callbacks":       x.Callbacks,
+		})
+	case *openapi2.T:
+		c := *x
+		c.Paths, c.Definitions, c.Parameters, c.Responses = nil, nil, nil, nil
+		shallow = c
+		fields = nonEmpty(map[string]any{
+			"paths":       x.Paths,
+			"definitions": x.Definitions,
+			"parameters":  x.Parameters,
+			"responses":   x.Responses,
+		})
+	default:
+		data, err := json.Marshal(v)
+		return rawJSON(data), err
+	}
+	data, err := json.Marshal(shallow)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := rawJSON(data).object()
+	if err != nil {
+		return nil, err
+	}
+	for k, f := range fields {
+		obj[k] = f
+	}
+	return lazyObject(obj), nil
+}
+
+// lazyObject is an object whose entries are marshaled individually, as
+// they are written, rather than as part of the object as a whole. Its
+// values are rawJSON, lazyObjects or the Go values to be marshaled.
+type lazyObject map[string]any
+
+func (v lazyObject) kind() byte {
+	return '{'
+}
+
+func (v lazyObject) isMapping() bool {
+	return len(v) > 0
+}
+
+func (v lazyObject) object() (map[string]any, error) {
+	return v, nil
+}
+
+func (v lazyObject) node(ko *keyOrder) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range orderKeys(ko, v) {
+		c, err := value(v[k])
+		if err != nil {
+			return nil, err
+		}
+		cn, err := c.node(ko.value(k))
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, scalarNode(k), cn)
+	}
+	return n, nil
+}
+
+// lazyMap returns a lazyObject for m, which must be a map with string
+// keys, eg. openapi3.Paths.
+func lazyMap(m any) lazyObject {
+	rv := reflect.ValueOf(m)
+	obj := make(lazyObject, rv.Len())
+	for it := rv.MapRange(); it.Next(); {
+		obj[it.Key().String()] = it.Value().Interface()
+	}
+	return obj
+}
+
+// nonEmpty returns the non-empty maps in fields as lazyObjects.
+func nonEmpty(fields map[string]any) map[string]any {
+	for k, f := range fields {
+		if reflect.ValueOf(f).Len() == 0 {
+			delete(fields, k)
+			continue
+		}
+		fields[k] = lazyMap(f)
+	}
+	return fields
+}
+
+var errMalformedJSON = errors.New("malformed JSON")
+
+// rawJSON is a value within the compact JSON produced by json.Marshal.
+type rawJSON []byte
+
+func (v rawJSON) kind() byte {
+	if len(v) == 0 {
+		return 0
+	}
+	return v[0]
+}
+
+// isMapping returns true if v is a non-empty object.
+func (v rawJSON) isMapping() bool {
+	return v.kind() == '{' && len(v) > 2
+}
+
+// next returns the index of the byte that follows the value starting
+// at v[i].
+func (v rawJSON) next(i int) int {
+	depth := 0
+	for ; i < len(v); i++ {
+		switch v[i] {
+		case '"':
+			for i++; i < len(v) && v[i] != '"'; i++ {
+				if v[i] == '\\' {
+					i++
+				}
+			}
+		case '{', '[':
+			depth++
+			continue
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case ',', ':':
+			if depth == 0 {
+				return i
+			}
+			continue
+		default:
+			continue
+		}
+		if depth == 0 {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// each calls fn for each of the values, or keys and values, contained
+// in the object or array v.
+func (v rawJSON) each(fn func(key, value rawJSON) error) error {
+	for i := 1; i < len(v)-1; {
+		var key rawJSON
+		if v[0] == '{' {
+			end := v.next(i)
+			if end == i || end >= len(v) || v[end] != ':' {
+				return errMalformedJSON
+			}
+			key, i = v[i:end], end+1
+		}
+		end := v.next(i)
+		if end == i {
+			return errMalformedJSON
+		}
+		if err := fn(key, v[i:end]); err != nil {
+			return err
+		}
+		i = end
+		if i < len(v) && v[i] == ',' {
+			i++
+		}
+	}
+	return nil
+}
+
+// object returns the entries of the object v.
+func (v rawJSON) object() (map[string]any, error) {
+	m := map[string]any{}
+	err := v.each(func(key, value rawJSON) error {
+		k, err := key.str()
+		m[k] = value
+		return err
+	})
+	return m, err
+}
+
+// str returns the string v.
+func (v rawJSON) str() (string, error) {
+	if len(v) >= 2 && v[0] == '"' && bytes.IndexByte(v, '\\') < 0 {
+		return string(v[1 : len(v)-1]), nil
+	}
+	var s string
+	err := json.Unmarshal(v, &s)
+	return s, err
+}
+
+// node returns the yaml.Node for v, as per orderedNode.
+func (v rawJSON) node(ko *keyOrder) (*yaml.Node, error) {
+	switch v.kind() {
+	case '{':
+		m, err := v.object()
+		if err != nil {
+			return nil, err
+		}
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range orderKeys(ko, m) {
+			c, err := m[k].(rawJSON).node(ko.value(k))
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, scalarNode(k), c)
+		}
+		return n, nil
+	case '[':
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		err := v.each(func(_, value rawJSON) error {
+			c, err := value.node(ko.elem())
+			n.Content = append(n.Content, c)
+			return err
+		})
+		return n, err
+	case '"':
+		s, err := v.str()
+		return scalarNode(s), err
+	case 't', 'f':
+		return scalarNode(v[0] == 't'), nil
+	case 'n':
+		return scalarNode(nil), nil
+	}
+	return scalarNode(json.Number(v)), nil
+}
+
+// yamlWriter writes YAML one mapping entry at a time, see Encoder.
+type yamlWriter struct {
+	w     *bufio.Writer
+	style FormatOptions
+}
+
+func (yw *yamlWriter) write(v jsonValue, ko *keyOrder) error {
+	if v.isMapping() {
+		return yw.mapping(v, ko, 0)
+	}
+	n, err := v.node(ko)
+	if err != nil {
+		return err
+	}
+	return yw.run(n, 0, false)
+}
+
+// mapping writes the entries of the non-empty object v indented by the
+// specified depth. The entries whose values are non-empty objects are
+// written in the same way, following their keys, provided that those
+// keys are written on a line by themselves. All other entries are
+// written in runs using yaml.v3.
+func (yw *yamlWriter) mapping(v jsonValue, ko *keyOrder, depth int) error {
+	m, err := v.object()
+	if err != nil {
+		return err
+	}
+	run := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range orderKeys(ko, m) {
+		c, err := value(m[k])
+		if err != nil {
+			return err
+		}
+		if !c.isMapping() || !simpleKey(k) {
+			n, err := c.node(ko.value(k))
+			if err != nil {
+				return err
+			}
+			run.Content = append(run.Content, scalarNode(k), n)
+			continue
+		}
+		// The key is written as the last entry of the run with an empty
+		// mapping in place of its value.
+		run.Content = append(run.Content, scalarNode(k), &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		if err := yw.run(run, depth, true); err != nil {
+			return err
+		}
+		if err := yw.mapping(c, ko.value(k), depth+1); err != nil {
+			return err
+		}
+		run = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	if len(run.Content) == 0 {
+		return nil
+	}
+	return yw.run(run, depth, false)
+}
+
+// run writes n indented by the specified depth. If key is set, only the
+// key of the last entry of n, which must be a mapping, is written for
+// that entry.
+func (yw *yamlWriter) run(n *yaml.Node, depth int, key bool) error {
+	shift := depth * yw.style.indent()
+	out, err := yw.style.formatYAML(n, shift)
+	if err != nil {
+		return err
+	}
+	if key {
+		if !bytes.HasSuffix(out, []byte(": {}\n")) || bytes.HasSuffix(out, []byte("\n: {}\n")) {
+			return fmt.Errorf("failed to format YAML key: %q", n.Content[len(n.Content)-2].Value)
+		}
+		out = append(out[:len(out)-len(" {}\n")], '\n')
+	}
+	indent := strings.Repeat(" ", shift)
+	for len(out) > 0 {
+		line := out[:lineLen(out)]
+		// Empty lines, eg. within literal block scalars, are not indented.
+		if len(line) != breakLen(line) {
+			yw.w.WriteString(indent)
+		}
+		yw.w.Write(line)
+		out = out[len(line):]
+	}
+	return nil
+}
+
+// breakLen returns the length of the line break, if any, that starts
+// text. As for gopkg.in/yaml.v3, NEL, LS and PS are line breaks, as are
+// CR and LF, and are followed by indentation when they occur within
+// scalars.
+func breakLen(text []byte) int {
+	switch {
+	case len(text) == 0:
+		return 0
+	case text[0] == '\n' || text[0] == '\r':
+		return 1
+	case bytes.HasPrefix(text, []byte("\u0085")):
+		return 2
+	case bytes.HasPrefix(text, []byte("\u2028")), bytes.HasPrefix(text, []byte("\u2029")):
+		return 3
+	}
+	return 0
+}
+
+// lineLen returns the length of the first line of text, including the
+// line break that ends it.
+func lineLen(text []byte) int {
+	for i := range text {
+		if n := breakLen(text[i:]); n > 0 {
+			return i + n
+		}
+	}
+	return len(text)
+}
+
+// simpleKey returns true if gopkg.in/yaml.v3 writes the key k on the same
+// line as its value rather than as a complex key, ie. ? <key>, which it
+// does for keys that span multiple lines or are longer than 128 bytes.
+func simpleKey(k string) bool {
+	return len(k) <= 128 && !strings.ContainsAny(k, "\r\n\u0085\u2028\u2029")
+}
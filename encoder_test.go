@@ -0,0 +1,196 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+// writeCounter records the number and size of the writes made to it.
+type writeCounter struct {
+	writes, largest, total int
+}
+
+func (wc *writeCounter) Write(p []byte) (int, error) {
+	wc.writes++
+	wc.total += len(p)
+	if len(p) > wc.largest {
+		wc.largest = len(p)
+	}
+	return len(p), nil
+}
+
+func readGolden(t *testing.T, name string) []byte {
+	buf, err := os.ReadFile(filepath.Join("testdata", "encoder", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestEncoder(t *testing.T) {
+	// The golden files in testdata/encoder were created by FormatV3 and
+	// FormatV2 as they were before they were implemented using Encoder.
+	for _, filename := range []string{"encoder.yaml", "kitchensink.yaml", "petstore-expanded.yaml", "openapi31.yaml"} {
+		doc := load(filename)
+		base := strings.TrimSuffix(filename, ".yaml")
+		for _, tc := range []struct {
+			golden string
+			isYAML bool
+			style  openapi.FormatOptions
+		}{
+			{base + ".json", false, openapi.FormatOptions{}},
+			{base + ".yaml", true, openapi.FormatOptions{}},
+			{base + ".indent.json", false, openapi.FormatOptions{Indent: 4, PrettyJSON: true}},
+			{base + ".indent.yaml", true, openapi.FormatOptions{Indent: 4, PrettyJSON: true}},
+			{base + ".styled.yaml", true, openapi.FormatOptions{Indent: 3, LiteralBlocks: true, FlowArrays: true, LineWidth: 50}},
+		} {
+			out := &bytes.Buffer{}
+			if err := openapi.NewEncoder(out, tc.isYAML, openapi.FormatStyle(tc.style)).EncodeV3(doc); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := out.String(), string(readGolden(t, tc.golden)); got != want {
+				t.Errorf("%v: got:\n%s\nwant:\n%s", tc.golden, got, want)
+			}
+		}
+
+		// Pretty printed JSON must be the same as that produced by
+		// json.Indent.
+		want := &bytes.Buffer{}
+		if err := json.Indent(want, readGolden(t, base+".json"), "", "   "); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		style := openapi.FormatOptions{Indent: 3, PrettyJSON: true}
+		if err := openapi.NewEncoder(out, false, openapi.FormatStyle(style)).EncodeV3(doc); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != want.String() {
+			t.Errorf("%v: got %v, want %v", filename, got, want)
+		}
+	}
+
+	doc := loadV2("v2kitchensink.json")
+	for _, isYAML := range []bool{false, true} {
+		out := &bytes.Buffer{}
+		if err := openapi.NewEncoder(out, isYAML).EncodeV2(doc); err != nil {
+			t.Fatal(err)
+		}
+		golden := "v2kitchensink.json"
+		if isYAML {
+			golden = "v2kitchensink.yaml"
+		}
+		if got, want := out.String(), string(readGolden(t, golden)); got != want {
+			t.Errorf("%v: got:\n%s\nwant:\n%s", golden, got, want)
+		}
+	}
+
+	for _, isYAML := range []bool{false, true} {
+		err := openapi.NewEncoder(errWriter{}, isYAML).EncodeV3(load("api.yaml"))
+		if err == nil || !strings.Contains(err.Error(), "write failed") {
+			t.Errorf("%v: missing or wrong error: %v", isYAML, err)
+		}
+	}
+}
+
+func TestEncoderIncremental(t *testing.T) {
+	doc := load("benchling.yaml")
+	for _, isYAML := range []bool{false, true} {
+		for _, style := range []openapi.FormatOptions{
+			{},
+			{LiteralBlocks: true, FlowArrays: true, LineWidth: 80},
+		} {
+			wc := &writeCounter{}
+			if err := openapi.NewEncoder(wc, isYAML, openapi.FormatStyle(style)).EncodeV3(doc); err != nil {
+				t.Fatal(err)
+			}
+			if wc.total < 500*1024 || wc.largest > 64*1024 || wc.writes < 100 {
+				t.Errorf("%v: %+v: output was not written incrementally: %v writes, largest %v, total %v", isYAML, style, wc.writes, wc.largest, wc.total)
+			}
+		}
+	}
+}
+
+func TestEncoderLineBreaks(t *testing.T) {
+	// gopkg.in/yaml.v3 indents the text that follows line breaks other
+	// than \n, eg. LS, within scalars.
+	doc := map[string]any{"a": map[string]any{"b": map[string]any{"c": "first\u2028second", "d": "x"}}}
+	got, err := openapi.AsYAML(2, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a:\n  b:\n    c: 'first\u2028      second'\n    d: x\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// heapSampler records the largest heap size observed whilst it is being
+// written to.
+type heapSampler struct {
+	writes, total int
+	peak          uint64
+}
+
+func (hs *heapSampler) Write(p []byte) (int, error) {
+	if hs.writes%16 == 0 {
+		if h := heapAlloc(); h > hs.peak {
+			hs.peak = h
+		}
+	}
+	hs.writes++
+	hs.total += len(p)
+	return len(p), nil
+}
+
+func heapAlloc() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+func TestEncoderMemory(t *testing.T) {
+	// A document whose paths all refer to the same path item is small in
+	// memory but large when encoded, so the heap used to encode it must
+	// be much smaller than its encoding if it is encoded field by field.
+	doc := load("petstore-expanded.yaml")
+	item := doc.Paths["/pets"]
+	doc.Paths = openapi3.Paths{}
+	for i := 0; i < 4000; i++ {
+		doc.Paths[fmt.Sprintf("/pets/%v", i)] = item
+	}
+	defer debug.SetGCPercent(debug.SetGCPercent(10))
+	for _, isYAML := range []bool{false, true} {
+		runtime.GC()
+		base := heapAlloc()
+		hs := &heapSampler{}
+		if err := openapi.NewEncoder(hs, isYAML).EncodeV3(doc); err != nil {
+			t.Fatal(err)
+		}
+		if hs.total < 10*1024*1024 {
+			t.Fatalf("%v: document is too small: %v", isYAML, hs.total)
+		}
+		if used := int(hs.peak) - int(base); used > hs.total/4 {
+			t.Errorf("%v: %v bytes of heap used to write %v bytes", isYAML, used, hs.total)
+		}
+	}
+}
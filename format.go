@@ -0,0 +1,267 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+type formatOptions struct {
+	sortKeys  bool
+	keyOrders map[string][]string
+	source    *Source
+	style     FormatOptions
+	err       error
+}
+
+// FormatOption represents an option for use when formatting a document.
+type FormatOption func(o *formatOptions)
+
+// FormatSortKeys controls whether the keys of every object are formatted
+// in lexicographic order rather than the conventional order described
+// for FormatV3.
+func FormatSortKeys(v bool) FormatOption {
+	return func(o *formatOptions) {
+		o.sortKeys = v
+	}
+}
+
+// FormatKeyOrder specifies the order of the keys for the named object,
+// eg. FormatKeyOrder(SchemaObject, "type", "description"), in place of
+// the default order for that object. Keys that are not listed follow
+// those that are in lexicographic order. The same names are used for
+// the corresponding objects in openapi2 documents, eg. DocumentObject
+// for the swagger document itself. FormatV3 and FormatV2 will return an
+// error if the object is not one of those defined by this package, ie.
+// DocumentObject, InfoObject etc.
+func FormatKeyOrder(object string, keys ...string) FormatOption {
+	return func(o *formatOptions) {
+		if _, ok := defaultKeyOrders[object]; !ok {
+			if o.err == nil {
+				o.err = fmt.Errorf("unsupported object for key ordering: %q", object)
+			}
+			return
+		}
+		if o.keyOrders == nil {
+			o.keyOrders = map[string][]string{}
+		}
+		o.keyOrders[object] = keys
+	}
+}
+
+// FormatSource specifies the source that the document being formatted
+// was read from. When formatting as YAML, FormatV3 will patch the source
+// with the changes made to the document, rather than formatting the
+// document afresh, so that its unchanged parts are reproduced byte for
+// byte, including comments, anchors, blank lines, quoting and key
+// order. Map entries and sequence items whose values are changed are
+// replaced in their entirety and any that are added are formatted in
+// the order used by FormatV3 after those already present. Anchors whose
+// contents are changed or removed are expanded in place of their
+// aliases. The source is ignored if it is not a YAML mapping in block
+// style, eg. if it is JSON.
+func FormatSource(src *Source) FormatOption {
+	return func(o *formatOptions) {
+		o.source = src
+	}
+}
+
+// FormatV3 formats the supplied document as YAML or JSON. The keys of
+// each object are formatted in the order conventionally used for
+// openapi documents, for example: openapi, info, servers, security, tags,
+// paths and components for the document itself, operations in HTTP
+// method order (get, put, post, delete, options, head, patch, trace) and
+// schema keywords starting with $ref, title, type, format, description,
+// properties and required. Keys with no conventional order, including
+// extensions and the names of paths, properties, responses etc, are
+// formatted in lexicographic order. FormatKeyOrder and FormatSortKeys
+// may be used to change the order, FormatStyle to change the style and
+// FormatSource to preserve the formatting of the document's source.
+func FormatV3(doc *openapi3.T, isYAML bool, opts ...FormatOption) ([]byte, error) {
+	return format(doc, isYAML, v3Format, opts)
+}
+
+// FormatV2 is like FormatV3 but for openapi2 (swagger) documents. The
+// conventional order for the document itself is swagger, info, host,
+// basePath, schemes, consumes, produces, security, tags, externalDocs,
+// paths, definitions, parameters, responses and securityDefinitions.
+func FormatV2(doc *openapi2.T, isYAML bool, opts ...FormatOption) ([]byte, error) {
+	return format(doc, isYAML, v2Format, opts)
+}
+
+// formatDialect represents the differences between formatting openapi2
+// and openapi3 documents.
+type formatDialect struct {
+	keyOrders func(orders map[string][]string) *keyOrder
+	// newDoc returns an empty document to unmarshal a source into.
+	newDoc func() any
+}
+
+var (
+	v2Format = formatDialect{
+		keyOrders: newKeyOrdersV2,
+		newDoc:    func() any { return &openapi2.T{} },
+	}
+	v3Format = formatDialect{
+		keyOrders: newKeyOrders,
+		newDoc:    func() any { return &openapi3.T{} },
+	}
+)
+
+func format(doc any, isYAML bool, dialect formatDialect, opts []FormatOption) ([]byte, error) {
+	out := &bytes.Buffer{}
+	err := NewEncoder(out, isYAML, opts...).encode(doc, dialect)
+	return out.Bytes(), err
+}
+
+// decodeJSON returns the result of marshaling doc to JSON and decoding
+// it, using json.Number for numbers. This roundtrip to/from json ensures
+// that corner cases are handled correctly. See: http://web.archive.org/web/20190603050330/http://ghodss.com/2014/the-right-way-to-handle-yaml-in-golang/
+func decodeJSON(doc any) (any, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&v)
+	return v, err
+}
+
+// orderedNode returns a yaml.Node for v, which must have been obtained by
+// decoding JSON using json.Number for numbers, with the keys of objects
+// in the order specified by ko.
+func orderedNode(v any, ko *keyOrder) *yaml.Node {
+	switch x := v.(type) {
+	case map[string]any:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range orderKeys(ko, x) {
+			n.Content = append(n.Content, scalarNode(k), orderedNode(x[k], ko.value(k)))
+		}
+		return n
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range x {
+			n.Content = append(n.Content, orderedNode(e, ko.elem()))
+		}
+		return n
+	}
+	return scalarNode(v)
+}
+
+// scalarNode returns a yaml.Node for a string, json.Number, bool or nil.
+func scalarNode(v any) *yaml.Node {
+	switch x := v.(type) {
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: x}
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(string(x), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(x)}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(x)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// writeJSON writes v as JSON with the keys of objects in the order
+// specified by ko. Nested values are indented by indent, starting at the
+// specified depth, or, if indent is empty, the JSON is compact. The
+// indented form is the same as that produced by json.Indent.
+func writeJSON(w *bufio.Writer, v jsonValue, ko *keyOrder, indent string, depth int) error {
+	newline := func(depth int) {
+		if len(indent) > 0 {
+			w.WriteByte('\n')
+			for i := 0; i < depth; i++ {
+				w.WriteString(indent)
+			}
+		}
+	}
+	// Only objects may be lazy.
+	raw, _ := v.(rawJSON)
+	switch v.kind() {
+	case '{':
+		m, err := v.object()
+		if err != nil {
+			return err
+		}
+		w.WriteByte('{')
+		for i, k := range orderKeys(ko, m) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			newline(depth + 1)
+			key, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+			w.Write(key)
+			w.WriteByte(':')
+			if len(indent) > 0 {
+				w.WriteByte(' ')
+			}
+			c, err := value(m[k])
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(w, c, ko.value(k), indent, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(m) > 0 {
+			newline(depth)
+		}
+		w.WriteByte('}')
+	case '[':
+		w.WriteByte('[')
+		n := 0
+		err := raw.each(func(_, elem rawJSON) error {
+			if n > 0 {
+				w.WriteByte(',')
+			}
+			n++
+			newline(depth + 1)
+			return writeJSON(w, elem, ko.elem(), indent, depth+1)
+		})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			newline(depth)
+		}
+		w.WriteByte(']')
+	case '"':
+		// Strings with escapes are decoded and marshaled again since
+		// json.Marshal does not always escape a string the same way
+		// twice, eg. invalid UTF-8 is first escaped as \ufffd.
+		if bytes.IndexByte(raw, '\\') < 0 {
+			w.Write(raw)
+			return nil
+		}
+		s, err := raw.str()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	default:
+		w.Write(raw)
+	}
+	return nil
+}
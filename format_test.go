@@ -5,6 +5,7 @@
 package openapi_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"github.com/getkin/kin-openapi/openapi2"
 	"github.com/getkin/kin-openapi/openapi2conv"
 	"github.com/getkin/kin-openapi/openapi3"
+	jsonyaml "github.com/invopop/yaml"
 	"gopkg.in/yaml.v3"
 )
 
@@ -91,7 +93,7 @@ func TestFormatStyle(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !strings.HasPrefix(string(buf), `{"components":{"schemas":{"ApiResponse`) {
+	if !strings.HasPrefix(string(buf), `{"openapi":"3.0.3","info":{"title":"Swagger Petstore"`) {
 		t.Fatalf("doesn't look like JSON: %s", buf)
 	}
 
@@ -110,7 +112,7 @@ func TestFormatStyle(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !strings.HasPrefix(string(buf), `components:`) {
+	if !strings.HasPrefix(string(buf), "openapi: 3.0.3\ninfo:\n") {
 		t.Fatalf("doesn't look like YAML: %s", buf)
 	}
 
@@ -126,3 +128,282 @@ func TestFormatStyle(t *testing.T) {
 	}
 
 }
+
+func TestFormatKeyOrder(t *testing.T) {
+	doc := load("petstore-expanded.yaml")
+	indexes := func(buf []byte, keys ...string) []int {
+		idx := []int{}
+		for _, k := range keys {
+			idx = append(idx, strings.Index(string(buf), k))
+		}
+		return idx
+	}
+	isOrdered := func(buf []byte, keys ...string) bool {
+		idx := indexes(buf, keys...)
+		for i := range idx {
+			if idx[i] < 0 || (i > 0 && idx[i] < idx[i-1]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	buf, err := openapi.FormatV3(doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, keys := range [][]string{
+		{"\nopenapi:", "\ninfo:", "\nservers:", "\npaths:", "\ncomponents:"},
+		{"    get:\n      summary: Returns all pets", "    post:\n      summary: Creates a new pet"},
+		{"  title: Swagger Petstore", "  description: A sample API", "  version: 1.0.0"},
+		{"        - name: tags\n          in: query\n          description: tags to filter by"},
+		{"    NewPet:\n      properties:\n        name:\n          type: string\n          description: Name of the pet\n", "      required:\n        - name\n"},
+	} {
+		if !isOrdered(append([]byte{'\n'}, buf...), keys...) {
+			t.Errorf("%q: not found in order in:\n%s", keys, buf)
+		}
+	}
+
+	buf, err = openapi.FormatV3(doc, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isOrdered(buf, `{"openapi":`, `"info":`, `"servers":`, `"paths":`, `"components":`) {
+		t.Errorf("not in order: %s", buf)
+	}
+
+	buf, err = openapi.FormatV3(doc, true, openapi.FormatSortKeys(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isOrdered(buf, "components:", "info:", "openapi:", "paths:", "servers:") {
+		t.Errorf("not sorted: %s", buf)
+	}
+
+	buf, err = openapi.FormatV3(doc, true,
+		openapi.FormatKeyOrder(openapi.SchemaObject, "required", "type"),
+		openapi.FormatKeyOrder(openapi.DocumentObject, "paths"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(buf), "paths:") ||
+		!isOrdered(buf, "    NewPet:\n      required:\n        - name\n      properties:\n        name:\n          type: string\n") {
+		t.Errorf("not in order: %s", buf)
+	}
+
+	_, err = openapi.FormatV3(doc, true, openapi.FormatKeyOrder("nothing"))
+	if err == nil || !strings.Contains(err.Error(), `unsupported object for key ordering: "nothing"`) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestFormatSource(t *testing.T) {
+	readSource := func(filename string) *openapi.Source {
+		src, err := openapi.ReadSource(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return src
+	}
+	// Unmodified documents should be reproduced exactly.
+	for _, filename := range []string{"api.yaml", "benchling.yaml", "petstore-expanded.yaml", "kitchensink.yaml", "openapi31.yaml", "patch.yaml"} {
+		buf, err := openapi.FormatV3(load(filename), true, openapi.FormatSource(readSource(filename)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(buf), string(data); got != want {
+			t.Errorf("%v: formatted document differs from the original", filename)
+		}
+	}
+
+	doc := load("patch.yaml")
+	doc.Info.Title = "patched"
+	op := doc.Paths["/pets"].Get
+	op.Parameters = append(op.Parameters, &openapi3.ParameterRef{
+		Value: openapi3.NewQueryParameter("offset").WithSchema(openapi3.NewIntegerSchema())})
+	pet := doc.Components.Schemas["Pet"].Value
+	delete(pet.Properties, "tag")
+	// name is anchored and its alias, nickname, must be expanded.
+	pet.Properties["name"].Value.MaxLength = openapi3.Uint64Ptr(32)
+	pet.Required = []string{"name"}
+	doc.Components.Schemas["Error"].Value.Properties["code"].Value.Format = "int32"
+	buf, err := openapi.FormatV3(doc, true, openapi.FormatSource(readSource("patch.yaml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "patch-formatted.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf); got != string(want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	ndoc, err := openapi3.NewLoader().LoadFromData(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ndoc.Components.Schemas["Pet"].Value.Properties["nickname"].Value.MaxLength, uint64(64); got == nil || *got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// JSON sources are ignored.
+	data, err := openapi.FormatV3(doc, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := openapi.NewSource(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err = openapi.FormatV3(doc, true, openapi.FormatSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := openapi.FormatV3(doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), string(plain); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFormatV2(t *testing.T) {
+	doc := loadV2("v2swagger.json")
+	for _, isYAML := range []bool{false, true} {
+		buf, err := openapi.FormatV2(doc, isYAML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix := `{"swagger":"2.0","info":{"title":"Swagger Petstore"`
+		if isYAML {
+			prefix = "swagger: \"2.0\"\ninfo:\n  title: Swagger Petstore\n"
+		}
+		if !strings.HasPrefix(string(buf), prefix) {
+			t.Fatalf("%v: unexpected prefix: %.100s", isYAML, buf)
+		}
+		for _, keys := range [][]string{
+			{"host", "basePath", "schemes", "externalDocs", "paths", "definitions", "securityDefinitions"},
+			{"summary", "operationId", "consumes", "produces", "parameters", "responses", "security"},
+		} {
+			prev := -1
+			for _, k := range keys {
+				idx := strings.Index(string(buf), k)
+				if idx <= prev {
+					t.Errorf("%v: %v: out of order", isYAML, k)
+				}
+				prev = idx
+			}
+		}
+		var ndoc openapi2.T
+		if err := jsonyaml.Unmarshal(buf, &ndoc); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(doc, &ndoc) {
+			t.Errorf("%v: document changed by formatting", isYAML)
+		}
+	}
+
+	// Preserve the formatting of a YAML source.
+	buf, err := openapi.FormatV2(doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Replace(string(buf), "basePath: /v2\n", "basePath: /v2 # comment\n", 1)
+	src, err := openapi.NewSource([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Info.Title = "Petstore"
+	buf, err = openapi.FormatV2(doc, true, openapi.FormatSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), strings.Replace(data, "title: Swagger Petstore", "title: Petstore", 1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	for _, filename := range []string{"api.yaml", "kitchensink.yaml", "petstore-expanded.yaml"} {
+		want, err := load(filename).MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, style := range []openapi.FormatOptions{
+			{},
+			{Indent: 4},
+			{Indent: 3, LiteralBlocks: true},
+			{LiteralBlocks: true, FlowArrays: true, LineWidth: 80},
+			{Indent: 4, LiteralBlocks: true, FlowArrays: true, LineWidth: 40},
+		} {
+			buf, err := openapi.FormatV3(load(filename), true, openapi.FormatStyle(style))
+			if err != nil {
+				t.Fatalf("%v: %+v: %v", filename, style, err)
+			}
+			doc, err := openapi3.NewLoader().LoadFromData(buf)
+			if err != nil {
+				t.Fatalf("%v: %+v: %v", filename, style, err)
+			}
+			got, err := doc.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%v: %+v: document changed by formatting", filename, style)
+			}
+		}
+	}
+
+	doc := load("api.yaml")
+	for _, tc := range []struct {
+		isYAML bool
+		style  openapi.FormatOptions
+		want   string
+	}{
+		{true, openapi.FormatOptions{Indent: 4}, "openapi: 3.0.0\ninfo:\n    title: Custom Client Type Example\n"},
+		{true, openapi.FormatOptions{}, `description: "\ndouble quotes\ntrailing double quotes\n"`},
+		{true, openapi.FormatOptions{FlowArrays: true}, "required: [name]\n"},
+		{false, openapi.FormatOptions{PrettyJSON: true}, "{\n  \"openapi\": \"3.0.0\",\n  \"info\": {\n    \"title\""},
+		{false, openapi.FormatOptions{PrettyJSON: true, Indent: 4}, "{\n    \"openapi\": \"3.0.0\",\n    \"info\": {\n        \"title\""},
+	} {
+		buf, err := openapi.FormatV3(doc, tc.isYAML, openapi.FormatStyle(tc.style))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(buf), tc.want) {
+			t.Errorf("%+v: %s does not contain %q", tc.style, buf, tc.want)
+		}
+	}
+
+	out, err := openapi.AsYAML(2, map[string]any{
+		"count":       3,
+		"description": "The quick brown fox jumps over the lazy dog, then does it again.",
+		"enum":        []string{"a very long enum value", "another very long enum value"},
+		"quoted":      "Has a: colon and then some more words to fold over lines.",
+		"required":    []string{"id", "name"},
+		"text":        "line one\nline two\n",
+	}, openapi.FormatStyle(openapi.FormatOptions{LiteralBlocks: true, FlowArrays: true, LineWidth: 40}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out, `count: 3
+description: The quick brown fox jumps
+  over the lazy dog, then does it again.
+enum:
+  - a very long enum value
+  - another very long enum value
+quoted: 'Has a: colon and then some
+  more words to fold over lines.'
+required: [id, name]
+text: |
+  line one
+  line two
+`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
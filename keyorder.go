@@ -0,0 +1,267 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import "sort"
+
+// keyOrder specifies the order in which the keys of an object are
+// formatted and the keyOrders for the values of those keys. Keys that
+// are not listed, including extensions, follow those that are, in
+// lexicographic order. A keyOrder with no keys, but with elems, is used
+// for maps and arrays whose elements are all of the same kind, eg. the
+// properties of a schema or the parameters of an operation.
+type keyOrder struct {
+	keys   []string
+	values map[string]*keyOrder
+	elems  *keyOrder
+}
+
+// orderKeys returns the keys of m in the order specified by ko, ko may
+// be nil in which case the keys are sorted.
+func orderKeys[V any](ko *keyOrder, m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	if ko != nil {
+		for _, k := range ko.keys {
+			if _, ok := m[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	n := len(keys)
+	for k := range m {
+		if !ko.listed(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[n:])
+	return keys
+}
+
+func (ko *keyOrder) listed(key string) bool {
+	if ko == nil {
+		return false
+	}
+	for _, k := range ko.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the keyOrder for the value of key.
+func (ko *keyOrder) value(key string) *keyOrder {
+	if ko == nil {
+		return nil
+	}
+	if ko.elems != nil {
+		return ko.elems
+	}
+	return ko.values[key]
+}
+
+// elem returns the keyOrder for the elements of an array.
+func (ko *keyOrder) elem() *keyOrder {
+	if ko == nil {
+		return nil
+	}
+	return ko.elems
+}
+
+// The names of the objects whose key order may be changed using
+// FormatKeyOrder.
+const (
+	DocumentObject       = "document"
+	InfoObject           = "info"
+	ServerObject         = "server"
+	TagObject            = "tag"
+	PathItemObject       = "pathItem"
+	OperationObject      = "operation"
+	ParameterObject      = "parameter"
+	HeaderObject         = "header"
+	RequestBodyObject    = "requestBody"
+	ResponseObject       = "response"
+	MediaTypeObject      = "mediaType"
+	ComponentsObject     = "components"
+	SchemaObject         = "schema"
+	SecuritySchemeObject = "securityScheme"
+)
+
+// httpMethods lists the HTTP methods that may be used for operations in
+// the order in which they are formatted.
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var infoOrders = map[string]*keyOrder{
+	"contact": {keys: []string{"name", "url", "email"}},
+	"license": {keys: []string{"name", "identifier", "url"}},
+}
+
+var defaultKeyOrders = map[string][]string{
+	DocumentObject: {"openapi", "info", "jsonSchemaDialect", "servers", "security", "tags", "externalDocs", "paths", "webhooks", "components"},
+	InfoObject:     {"title", "summary", "description", "termsOfService", "contact", "license", "version"},
+	ServerObject:   {"url", "description", "variables"},
+	TagObject:      {"name", "description", "externalDocs"},
+	PathItemObject: append([]string{"$ref", "summary", "description", "servers", "parameters"}, httpMethods...),
+	OperationObject: {"tags", "summary", "description", "externalDocs", "operationId",
+		"parameters", "requestBody", "responses", "callbacks", "deprecated", "security", "servers"},
+	ParameterObject: {"$ref", "name", "in", "description", "required", "deprecated", "allowEmptyValue",
+		"style", "explode", "allowReserved", "schema", "example", "examples", "content"},
+	HeaderObject: {"$ref", "description", "required", "deprecated", "allowEmptyValue",
+		"style", "explode", "allowReserved", "schema", "example", "examples", "content"},
+	RequestBodyObject: {"$ref", "description", "required", "content"},
+	ResponseObject:    {"$ref", "description", "headers", "content", "links"},
+	MediaTypeObject:   {"schema", "example", "examples", "encoding"},
+	ComponentsObject: {"schemas", "responses", "parameters", "examples", "requestBodies",
+		"headers", "securitySchemes", "links", "callbacks", "pathItems"},
+	SchemaObject: {"$ref", "title", "type", "format", "description",
+		"properties", "required", "additionalProperties", "items",
+		"allOf", "oneOf", "anyOf", "not", "discriminator",
+		"enum", "const", "default", "nullable",
+		"multipleOf", "minimum", "exclusiveMinimum", "maximum", "exclusiveMaximum",
+		"minLength", "maxLength", "pattern",
+		"minItems", "maxItems", "uniqueItems",
+		"minProperties", "maxProperties",
+		"readOnly", "writeOnly", "deprecated",
+		"example", "examples", "externalDocs", "xml"},
+	SecuritySchemeObject: {"$ref", "type", "description", "name", "in", "scheme", "bearerFormat", "flows", "openIdConnectUrl"},
+}
+
+// defaultKeyOrdersV2 is used for openapi2 documents in place of the
+// orders in defaultKeyOrders for the same objects.
+var defaultKeyOrdersV2 = map[string][]string{
+	DocumentObject: {"swagger", "info", "host", "basePath", "schemes", "consumes", "produces",
+		"security", "tags", "externalDocs", "paths", "definitions", "parameters", "responses", "securityDefinitions"},
+	OperationObject: {"tags", "summary", "description", "externalDocs", "operationId",
+		"consumes", "produces", "parameters", "responses", "schemes", "deprecated", "security"},
+	ParameterObject: {"$ref", "name", "in", "description", "required", "schema",
+		"type", "format", "allowEmptyValue", "items", "collectionFormat", "default"},
+	HeaderObject:         {"description", "type", "format", "items", "collectionFormat", "default"},
+	ResponseObject:       {"$ref", "description", "schema", "headers", "examples"},
+	SecuritySchemeObject: {"type", "description", "name", "in", "flow", "authorizationUrl", "tokenUrl", "scopes"},
+}
+
+func elems(o *keyOrder) *keyOrder {
+	return &keyOrder{elems: o}
+}
+
+// newObjectOrders returns a keyOrder, with no values, for each of the
+// objects in defaults, using the supplied order in place of the default
+// one where specified.
+func newObjectOrders(orders map[string][]string, defaults ...map[string][]string) map[string]*keyOrder {
+	ko := map[string]*keyOrder{}
+	for _, d := range defaults {
+		for name, keys := range d {
+			if k, ok := orders[name]; ok {
+				keys = k
+			}
+			ko[name] = &keyOrder{keys: keys}
+		}
+	}
+	return ko
+}
+
+// newKeyOrders returns the keyOrder for an openapi3 document, using the
+// supplied key orders for each object in place of the default ones.
+func newKeyOrders(orders map[string][]string) *keyOrder {
+	ko := newObjectOrders(orders, defaultKeyOrders)
+	doc, pathItem, op, schema := ko[DocumentObject], ko[PathItemObject], ko[OperationObject], ko[SchemaObject]
+	params, headers := elems(ko[ParameterObject]), elems(ko[HeaderObject])
+	content := elems(ko[MediaTypeObject])
+	schemas := elems(schema)
+	responses := elems(ko[ResponseObject])
+	pathItems := elems(pathItem)
+
+	ko[InfoObject].values = infoOrders
+	doc.values = map[string]*keyOrder{
+		"info":       ko[InfoObject],
+		"servers":    elems(ko[ServerObject]),
+		"tags":       elems(ko[TagObject]),
+		"paths":      pathItems,
+		"webhooks":   pathItems,
+		"components": ko[ComponentsObject],
+	}
+	pathItem.values = map[string]*keyOrder{
+		"servers":    elems(ko[ServerObject]),
+		"parameters": params,
+	}
+	for _, m := range httpMethods {
+		pathItem.values[m] = op
+	}
+	op.values = map[string]*keyOrder{
+		"servers":     elems(ko[ServerObject]),
+		"parameters":  params,
+		"requestBody": ko[RequestBodyObject],
+		"responses":   responses,
+		"callbacks":   elems(pathItems),
+	}
+	ko[ParameterObject].values = map[string]*keyOrder{"schema": schema, "content": content}
+	ko[HeaderObject].values = map[string]*keyOrder{"schema": schema, "content": content}
+	ko[RequestBodyObject].values = map[string]*keyOrder{"content": content}
+	ko[ResponseObject].values = map[string]*keyOrder{"headers": headers, "content": content}
+	ko[MediaTypeObject].values = map[string]*keyOrder{"schema": schema}
+	ko[ComponentsObject].values = map[string]*keyOrder{
+		"schemas":         schemas,
+		"responses":       responses,
+		"parameters":      params,
+		"requestBodies":   elems(ko[RequestBodyObject]),
+		"headers":         headers,
+		"securitySchemes": elems(ko[SecuritySchemeObject]),
+		"callbacks":       elems(pathItems),
+		"pathItems":       pathItems,
+	}
+	setSchemaOrders(schema)
+	return doc
+}
+
+// newKeyOrdersV2 returns the keyOrder for an openapi2 document, using the
+// supplied key orders for each object in place of the default ones.
+func newKeyOrdersV2(orders map[string][]string) *keyOrder {
+	ko := newObjectOrders(orders, defaultKeyOrders, defaultKeyOrdersV2)
+	doc, pathItem, op, schema := ko[DocumentObject], ko[PathItemObject], ko[OperationObject], ko[SchemaObject]
+	params := elems(ko[ParameterObject])
+	responses := elems(ko[ResponseObject])
+	ko[InfoObject].values = infoOrders
+	doc.values = map[string]*keyOrder{
+		"info":                ko[InfoObject],
+		"tags":                elems(ko[TagObject]),
+		"paths":               elems(pathItem),
+		"definitions":         elems(schema),
+		"parameters":          params,
+		"responses":           responses,
+		"securityDefinitions": elems(ko[SecuritySchemeObject]),
+	}
+	pathItem.values = map[string]*keyOrder{"parameters": params}
+	for _, m := range httpMethods {
+		pathItem.values[m] = op
+	}
+	op.values = map[string]*keyOrder{
+		"parameters": params,
+		"responses":  responses,
+	}
+	ko[ParameterObject].values = map[string]*keyOrder{"schema": schema, "items": schema}
+	ko[HeaderObject].values = map[string]*keyOrder{"items": schema}
+	ko[ResponseObject].values = map[string]*keyOrder{"schema": schema, "headers": elems(ko[HeaderObject])}
+	setSchemaOrders(schema)
+	return doc
+}
+
+func setSchemaOrders(schema *keyOrder) {
+	schemas := elems(schema)
+	schema.values = map[string]*keyOrder{
+		"properties":           schemas,
+		"additionalProperties": schema,
+		"items":                schema,
+		"allOf":                schemas,
+		"oneOf":                schemas,
+		"anyOf":                schemas,
+		"not":                  schema,
+		"$defs":                schemas,
+		"prefixItems":          schemas,
+		"if":                   schema,
+		"then":                 schema,
+		"else":                 schema,
+		"dependentSchemas":     schemas,
+	}
+}
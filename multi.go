@@ -0,0 +1,114 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// MultiVisitor is one of the visitors used by a walker created by
+// NewMultiWalker.
+type MultiVisitor struct {
+	// Visit is called for every node that is not pruned.
+	Visit ContextVisitor
+	// Prune, if not nil, returns true for nodes that need not be visited,
+	// in which case neither the node nor its children are passed to Visit.
+	Prune func(node any) bool
+}
+
+// NewMultiWalker returns a Walker that calls each of the supplied
+// visitors, in turn, for every node of a single walk of a document. Each
+// visitor sees the effects of any changes made to a node by the visitors
+// that precede it. The visitors are independent of each other in that:
+//
+//   - SkipNode, or pruning, skips the children of the current node for
+//     that visitor only.
+//   - SkipAll, or returning false, stops the walk for that visitor only.
+//
+// The walk descends into the children of a node as long as at least one
+// of the visitors is to visit them and stops once all of the visitors have
+// stopped. Any other error stops the walk and is returned by WalkContext.
+// NewMultiWalker accepts the same options as NewWalker and
+// WalkerPostVisit and WalkerCycleVisitor apply to the walk as a whole.
+func NewMultiWalker(visitors []MultiVisitor, opts ...WalkerOption) Walker {
+	mv := multiVisitor(append([]MultiVisitor{}, visitors...))
+	return NewContextWalker(mv.visit, opts...)
+}
+
+type multiVisitor []MultiVisitor
+
+// multiState records the progress of each of the visitors used by a
+// multi-walker.
+type multiState struct {
+	// skip records, for each visitor, the depth of the node whose
+	// children are being skipped, or -1 if none are.
+	skip []int
+	// stopped records, for each visitor, whether it has stopped. It is
+	// shared with any concurrent workers.
+	stopped []atomic.Bool
+}
+
+func (ms *multiState) fork() *multiState {
+	if ms == nil {
+		return nil
+	}
+	return &multiState{skip: append([]int{}, ms.skip...), stopped: ms.stopped}
+}
+
+func (mv multiVisitor) state(st *walkState) *multiState {
+	if st.multi == nil {
+		st.multi = &multiState{
+			skip:    make([]int, len(mv)),
+			stopped: make([]atomic.Bool, len(mv)),
+		}
+		for i := range st.multi.skip {
+			st.multi.skip[i] = -1
+		}
+	}
+	return st.multi
+}
+
+func (mv multiVisitor) visit(ctx context.Context, path []string, parent, node any) (bool, error) {
+	st := walkStateFromContext(ctx)
+	ms := mv.state(st)
+	depth := len(st.ancestors)
+	active, descend := 0, false
+	for i, v := range mv {
+		if ms.stopped[i].Load() {
+			continue
+		}
+		active++
+		if ms.skip[i] >= 0 {
+			if depth > ms.skip[i] {
+				continue
+			}
+			ms.skip[i] = -1
+		}
+		if v.Prune != nil && v.Prune(node) {
+			ms.skip[i] = depth
+			continue
+		}
+		ok, err := v.Visit(ctx, path, parent, node)
+		switch {
+		case err == SkipNode:
+			ms.skip[i] = depth
+		case err == SkipAll || (err == nil && !ok):
+			ms.stopped[i].Store(true)
+			active--
+		case err != nil:
+			return false, err
+		default:
+			descend = true
+		}
+	}
+	if active == 0 {
+		return false, nil
+	}
+	if !descend {
+		return true, SkipNode
+	}
+	return true, nil
+}
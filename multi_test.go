@@ -0,0 +1,129 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestMultiWalker(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	opts := []openapi.WalkerOption{openapi.WalkerOrder(openapi.SortedOrder)}
+
+	// Each visitor must see the same nodes as it would if it were used
+	// for a walk of its own.
+	newVisitors := func() ([]*testVisitor, []openapi.MultiVisitor) {
+		all, skip, stop, typed := &testVisitor{}, &testVisitor{}, &testVisitor{}, &testVisitor{}
+		return []*testVisitor{all, skip, stop, typed}, []openapi.MultiVisitor{
+			{Visit: func(ctx context.Context, path []string, parent, node any) (bool, error) {
+				return all.visitor(path, parent, node)
+			}},
+			{Visit: func(ctx context.Context, path []string, parent, node any) (bool, error) {
+				skip.visitor(path, parent, node)
+				if strings.Join(path, ":") == "components" {
+					return true, openapi.SkipNode
+				}
+				return true, nil
+			}},
+			{Visit: func(ctx context.Context, path []string, parent, node any) (bool, error) {
+				stop.visitor(path, parent, node)
+				if _, ok := node.(*openapi3.Operation); ok {
+					return false, nil
+				}
+				return true, nil
+			}},
+			openapi.TypedVisitor{
+				Schema: func(path []string, parent any, schema *openapi3.SchemaRef) (bool, error) {
+					return typed.visitor(path, parent, schema)
+				},
+			}.MultiVisitor(),
+		}
+	}
+
+	multi, visitors := newVisitors()
+	if err := openapi.NewMultiWalker(visitors, opts...).Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	single, visitors := newVisitors()
+	for i, v := range visitors {
+		var err error
+		if i == len(visitors)-1 {
+			err = openapi.NewTypedWalker(openapi.TypedVisitor{
+				Schema: func(path []string, parent any, schema *openapi3.SchemaRef) (bool, error) {
+					return single[i].visitor(path, parent, schema)
+				},
+			}, opts...).Walk(doc)
+		} else {
+			err = openapi.NewContextWalker(v.Visit, opts...).Walk(doc)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range multi {
+		if len(single[i].paths) == 0 {
+			t.Errorf("%v: no nodes visited", i)
+		}
+		if got, want := multi[i].paths, single[i].paths; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestMultiWalkerStop(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	errFail := errors.New("fail")
+	visited := 0
+	count := openapi.MultiVisitor{Visit: func(ctx context.Context, path []string, parent, node any) (bool, error) {
+		visited++
+		return true, nil
+	}}
+	failAt := func(typ any, err error) openapi.MultiVisitor {
+		return openapi.MultiVisitor{Visit: func(ctx context.Context, path []string, parent, node any) (bool, error) {
+			if reflect.TypeOf(node) == reflect.TypeOf(typ) {
+				return false, err
+			}
+			return true, nil
+		}}
+	}
+
+	// The walk stops once all of the visitors have stopped.
+	posts := 0
+	err := openapi.NewMultiWalker([]openapi.MultiVisitor{
+		failAt(&openapi3.Info{}, openapi.SkipAll),
+		failAt(&openapi3.Components{}, nil),
+	}, openapi.WalkerPostVisit(func(path []string, parent, node any) (bool, error) {
+		posts++
+		return true, nil
+	})).Walk(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := posts, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Errors stop the walk for all visitors.
+	err = openapi.NewMultiWalker([]openapi.MultiVisitor{
+		failAt(&openapi3.Components{}, errFail), count,
+	}, openapi.WalkerOrder(openapi.SortedOrder)).Walk(doc)
+	var perr *openapi.PathError
+	if !errors.As(err, &perr) || !errors.Is(err, errFail) {
+		t.Fatalf("unexpected or missing error: %v", err)
+	}
+	if got, want := strings.Join(perr.Path, ":"), "components"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := visited, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// WalkerParallel specifies the number of goroutines used to walk the
+// independent subtrees of a document, namely the individual path items
+// in paths and the individual schemas in components:schemas, or in
+// definitions for swagger 2.0 documents. The default, or any value less
+// than 2, results in a sequential walk. When walking concurrently:
+//
+//   - visitors, including post and cycle visitors, are called
+//     concurrently and must be safe for concurrent use.
+//   - the nodes within each subtree are visited in the usual order, but
+//     the order in which the subtrees themselves are visited is undefined.
+//   - visitors may modify the node they are passed and its descendants,
+//     but not its ancestors, and in particular not the paths or schemas
+//     maps. Apply ignores this option for this reason.
+//   - with WalkerFollowRefs, a schema that is referred to from more than
+//     one subtree may be visited concurrently and must not be modified
+//     without additional synchronization.
+//   - if a visitor returns an error, or stops the walk, the walks of all
+//     other subtrees are stopped and the outcome of the first, in walk
+//     order, of the subtrees that failed is returned.
+func WalkerParallel(n int) WalkerOption {
+	return func(o *walkerOptions) {
+		o.parallel = n
+	}
+}
+
+// concurrent returns true if the children of path are to be walked
+// concurrently. Only the top-level paths and schemas are walked
+// concurrently.
+func (wn nodeWalker) concurrent(path []string) bool {
+	if wn.opts.parallel < 2 || wn.state.worker {
+		return false
+	}
+	switch len(path) {
+	case 1:
+		return path[0] == "paths" || path[0] == "definitions"
+	case 2:
+		return path[0] == "components" && path[1] == "schemas"
+	}
+	return false
+}
+
+// forEach calls visit for each of keys, the children of path, either
+// sequentially or concurrently, see WalkerParallel. The walker passed to
+// visit must be used for the child and its descendants.
+func (wn nodeWalker) forEach(path []string, keys []string, visit func(wn nodeWalker, path []string, key string) (bool, error)) (ok bool, err error) {
+	if !wn.concurrent(path) {
+		for _, k := range keys {
+			if ok, err = visit(wn, append(path, k), k); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	}
+	return wn.parallel(path, keys, visit)
+}
+
+func (wn nodeWalker) parallel(path []string, keys []string, visit func(wn nodeWalker, path []string, key string) (bool, error)) (bool, error) {
+	ctx, cancel := context.WithCancel(wn.state.ctx)
+	defer cancel()
+	type result struct {
+		done, ok bool
+		err      error
+	}
+	results := make([]result, len(keys))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < wn.opts.parallel && w < len(keys); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				fw := wn
+				fw.state = wn.state.fork(ctx)
+				// Each worker has its own copy of the path since the
+				// walker appends to it in place.
+				child := make([]string, len(path), len(path)+8)
+				copy(child, path)
+				ok, err := visit(fw, append(child, keys[i]), keys[i])
+				results[i] = result{done: true, ok: ok, err: err}
+				if !ok || err != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+dispatch:
+	for i := range keys {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indices)
+	wg.Wait()
+	if err := wn.state.ctx.Err(); err != nil {
+		return false, err
+	}
+	for _, r := range results {
+		// Subtrees that were interrupted because another one failed
+		// are ignored.
+		if !r.done || errors.Is(r.err, context.Canceled) {
+			continue
+		}
+		if !r.ok || r.err != nil {
+			return r.ok, r.err
+		}
+	}
+	return true, nil
+}
+
+// fork returns a copy of st for use by a worker walking a subtree, ctx
+// is used in place of the original context.
+func (st *walkState) fork(ctx context.Context) *walkState {
+	fst := &walkState{
+		schemas:    make(map[*openapi3.Schema]bool, len(st.schemas)),
+		values:     make(map[any]bool, len(st.values)),
+		ancestors:  append([]Ancestor{}, st.ancestors...),
+		refs:       append([]Ref{}, st.refs...),
+		traceMu:    st.traceMu,
+		multi:      st.multi.fork(),
+		decoded:    st.decoded,
+		pointers:   st.pointers,
+		reportPtrs: st.reportPtrs,
+		worker:     true,
+	}
+	for k, v := range st.schemas {
+		fst.schemas[k] = v
+	}
+	for k, v := range st.values {
+		fst.values[k] = v
+	}
+	fst.ctx = context.WithValue(ctx, walkStateKey{}, fst)
+	return fst
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type concurrentVisitor struct {
+	sync.Mutex
+	visits []string
+}
+
+func (cv *concurrentVisitor) visitor(ctx context.Context, path []string, parent, node any) (bool, error) {
+	cv.Lock()
+	defer cv.Unlock()
+	cv.visits = append(cv.visits, fmt.Sprintf("%v %T %T %v %v", strings.Join(path, ":"), parent, node,
+		len(openapi.Ancestors(ctx)), len(openapi.RefChain(ctx))))
+	return true, nil
+}
+
+func (cv *concurrentVisitor) sorted() []string {
+	sort.Strings(cv.visits)
+	return cv.visits
+}
+
+func TestWalkParallel(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		walk func(v openapi.ContextVisitor, opts ...openapi.WalkerOption) error
+	}{
+		{"benchling", func(v openapi.ContextVisitor, opts ...openapi.WalkerOption) error {
+			return openapi.NewContextWalker(v, opts...).WalkContext(ctx, loadYAML("benchling.yaml"))
+		}},
+		{"kitchensink", func(v openapi.ContextVisitor, opts ...openapi.WalkerOption) error {
+			opts = append(opts, openapi.WalkerFollowRefs(true))
+			return openapi.NewContextWalker(v, opts...).WalkContext(ctx, loadYAML("kitchensink.yaml"))
+		}},
+		{"all-fields", func(v openapi.ContextVisitor, opts ...openapi.WalkerOption) error {
+			opts = append(opts, openapi.WalkerAllFields(true))
+			return openapi.NewContextWalker(v, opts...).WalkContext(ctx, loadYAML("kitchensink.yaml"))
+		}},
+		{"v2", func(v openapi.ContextVisitor, opts ...openapi.WalkerOption) error {
+			return openapi.NewV2ContextWalker(v, opts...).WalkContext(ctx, loadV2("v2kitchensink.json"))
+		}},
+	} {
+		seq, par := &concurrentVisitor{}, &concurrentVisitor{}
+		if err := tc.walk(seq.visitor); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if err := tc.walk(par.visitor, openapi.WalkerParallel(8)); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if got, want := par.sorted(), seq.sorted(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: parallel and sequential walks differ", tc.name)
+		}
+	}
+}
+
+func TestWalkParallelShared31(t *testing.T) {
+	// The OpenAPI 3.1 keywords of a schema that is referred to by many
+	// others are decoded concurrently by the workers that reach it.
+	ctx := context.Background()
+	for _, all := range []bool{false, true} {
+		doc := loadYAML("shared31.yaml")
+		var mu sync.Mutex
+		var conds map[*openapi3.SchemaRef]bool
+		visit := func(path []string, node any) {
+			if sref, ok := node.(*openapi3.SchemaRef); ok && path[len(path)-1] == "if" {
+				mu.Lock()
+				conds[sref] = true
+				mu.Unlock()
+			}
+		}
+		seq, par := &concurrentVisitor{}, &concurrentVisitor{}
+		opts := []openapi.WalkerOption{openapi.WalkerFollowRefs(true), openapi.WalkerAllFields(all)}
+		if err := openapi.NewContextWalker(seq.visitor, opts...).WalkContext(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			par.visits = nil
+			conds = map[*openapi3.SchemaRef]bool{}
+			wk := openapi.NewContextWalker(func(ctx context.Context, path []string, parent, node any) (bool, error) {
+				visit(path, node)
+				return par.visitor(ctx, path, parent, node)
+			}, append(opts, openapi.WalkerParallel(8))...)
+			if err := wk.WalkContext(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := par.sorted(), seq.sorted(); !reflect.DeepEqual(got, want) {
+				t.Errorf("all fields %v: parallel and sequential walks differ", all)
+			}
+			// Every worker must see the same decoded value.
+			if got, want := len(conds), 1; got != want {
+				t.Errorf("all fields %v: got %v, want %v", all, got, want)
+			}
+		}
+		if _, ok := doc.Components.Schemas["Owner"].Value.Extensions["if"].(map[string]any); !ok {
+			t.Errorf("all fields %v: document was modified", all)
+		}
+	}
+}
+
+func TestWalkParallelErrors(t *testing.T) {
+	doc := loadYAML("benchling.yaml")
+	errFail := errors.New("fail")
+	failAt := func(paths ...string) openapi.Visitor {
+		return func(path []string, parent, node any) (bool, error) {
+			p := strings.Join(path, ":")
+			for _, fp := range paths {
+				if p == fp {
+					return false, errFail
+				}
+			}
+			return true, nil
+		}
+	}
+	opts := []openapi.WalkerOption{
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerParallel(4),
+	}
+
+	// The error from the first failing subtree, in walk order, is returned.
+	for i := 0; i < 10; i++ {
+		err := openapi.NewWalker(failAt(
+			"paths:/apps/{app_id}:get",
+			"paths:/aa-sequences:get",
+			"paths:/workflow-tasks:get"), opts...).Walk(doc)
+		var perr *openapi.PathError
+		if !errors.As(err, &perr) || !errors.Is(err, errFail) {
+			t.Fatalf("unexpected or missing error: %v", err)
+		}
+		if got, want := strings.Join(perr.Path, ":"), "paths:/aa-sequences:get"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	var mu sync.Mutex
+	visited, all := 0, 0
+	err := openapi.NewWalker(func(path []string, parent, node any) (bool, error) {
+		all++
+		return true, nil
+	}).Walk(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = openapi.NewWalker(func(path []string, parent, node any) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		visited++
+		if _, ok := node.(*openapi3.Operation); ok {
+			return true, openapi.SkipAll
+		}
+		return true, nil
+	}, opts...).Walk(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited > all/2 {
+		t.Errorf("walk was not stopped: %v of %v nodes visited", visited, all)
+	}
+
+	cctx, cancel := context.WithCancel(context.Background())
+	err = openapi.NewContextWalker(func(ctx context.Context, path []string, parent, node any) (bool, error) {
+		cancel()
+		return true, nil
+	}, opts...).WalkContext(cctx, doc)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	jsonyaml "github.com/invopop/yaml"
+	"gopkg.in/yaml.v3"
+)
+
+// patcher creates the edits required to change the source of a document
+// so that it represents a (possibly) modified version of that document.
+// The edits are made at the granularity of map entries and sequence
+// items in block style YAML, all other text is left untouched.
+type patcher struct {
+	data    []byte
+	lines   []int // offset of the start of each line.
+	indent  int
+	literal bool // see FormatOptions.LiteralBlocks.
+	// lost records the anchored nodes whose contents have been changed
+	// or removed and hence whose aliases must be expanded.
+	lost  map[*yaml.Node]bool
+	edits []edit
+	err   error // the first error encountered encoding YAML.
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// patchSource writes the source of src patched to represent v, ie. the
+// document decoded from JSON as per orderedNode, to w or returns false,
+// without writing anything, if the source cannot be patched. The source
+// is parsed as doc, an empty openapi2 or openapi3 document, to determine
+// the changes to be made. An error is returned if the YAML for a changed
+// or added value cannot be encoded.
+func patchSource(w io.Writer, src *Source, doc, v any, ko *keyOrder, style FormatOptions) (bool, error) {
+	if len(src.data) == 0 || !isBlock(src.root) || src.root.Kind != yaml.MappingNode {
+		return false, nil
+	}
+	base, err := src.base(doc)
+	if err != nil {
+		return false, nil
+	}
+	p := &patcher{
+		data:    src.data,
+		lines:   []int{0},
+		indent:  detectIndent(src.root),
+		literal: style.LiteralBlocks,
+		lost:    map[*yaml.Node]bool{},
+	}
+	for i, c := range p.data {
+		if c == '\n' {
+			p.lines = append(p.lines, i+1)
+		}
+	}
+	ok := p.patch(src.root, base, v, ko, len(p.data))
+	if p.err != nil {
+		return false, p.err
+	}
+	if !ok {
+		return false, nil
+	}
+	sort.SliceStable(p.edits, func(i, j int) bool {
+		return p.edits[i].start < p.edits[j].start
+	})
+	out := bufio.NewWriter(w)
+	prev := 0
+	for _, e := range p.edits {
+		out.Write(p.data[prev:e.start])
+		out.WriteString(e.text)
+		prev = e.end
+	}
+	out.Write(p.data[prev:])
+	return true, out.Flush()
+}
+
+// base returns the document represented by the source as it is parsed
+// into doc by the kin-openapi loader and then decoded as per FormatV3.
+// Changes to a document are determined relative to it rather than to the
+// source itself since the loader does not preserve all of the source,
+// eg. it omits fields whose values are false.
+func (s *Source) base(doc any) (any, error) {
+	if err := jsonyaml.Unmarshal(s.data, doc); err != nil {
+		return nil, err
+	}
+	return decodeJSON(doc)
+}
+
+// detectIndent returns the indentation used for the first nested block
+// mapping in the document, or 2 if there is none.
+func detectIndent(root *yaml.Node) int {
+	for i := 1; i < len(root.Content); i += 2 {
+		v := root.Content[i]
+		if v.Kind == yaml.MappingNode && isBlock(v) && v.Column > root.Column {
+			return v.Column - root.Column
+		}
+	}
+	return 2
+}
+
+func isBlock(n *yaml.Node) bool {
+	return (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) &&
+		n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+// offset returns the offset in the source of the start of n.
+func (p *patcher) offset(n *yaml.Node) int {
+	off := p.lines[n.Line-1]
+	for col := 1; col < n.Column && off < len(p.data); col++ {
+		_, size := utf8.DecodeRune(p.data[off:])
+		off += size
+	}
+	return off
+}
+
+// lineStart returns the offset of the start of the line containing off.
+func (p *patcher) lineStart(off int) int {
+	i := sort.SearchInts(p.lines, off+1) - 1
+	return p.lines[i]
+}
+
+// contentEnd returns the end of the text of a node that starts at start,
+// in column col, and that extends no further than end. Trailing blank
+// lines and comments indented no further than col are excluded since
+// they precede whatever follows the node.
+func (p *patcher) contentEnd(start, col, end int) int {
+	first := p.lineStart(start)
+	for end > first {
+		ls := p.lineStart(end - 1)
+		if ls <= first {
+			break
+		}
+		line := p.data[ls:end]
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(bytes.TrimSpace(line)) > 0 && (trimmed[0] != '#' || len(line)-len(trimmed) >= col) {
+			break
+		}
+		end = ls
+	}
+	return end
+}
+
+// prefixed returns true if the text preceding off on its line consists
+// only of the supplied characters.
+func (p *patcher) prefixed(off int, chars string) bool {
+	return len(bytes.Trim(p.data[p.lineStart(off):off], chars)) == 0
+}
+
+func (p *patcher) add(start, end int, text string) {
+	p.edits = append(p.edits, edit{start: start, end: end, text: text})
+}
+
+// fail records err, if it is the first error encountered, and returns
+// false so that patching is abandoned.
+func (p *patcher) fail(err error) bool {
+	if p.err == nil {
+		p.err = err
+	}
+	return false
+}
+
+// patch records the edits required to change n, whose text extends no
+// further than end, and which the loader parsed as base, to represent v.
+// It returns false if n cannot be changed in place and must instead be
+// replaced.
+func (p *patcher) patch(n *yaml.Node, base, v any, ko *keyOrder, end int) bool {
+	if reflect.DeepEqual(base, v) && !p.hasLostAlias(n) {
+		return true
+	}
+	if len(n.Anchor) > 0 {
+		p.lost[n] = true
+	}
+	if !isBlock(n) {
+		return false
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		b, _ := base.(map[string]any)
+		return n.Kind == yaml.MappingNode && len(x) > 0 && p.patchMapping(n, b, x, ko, end)
+	case []any:
+		b, _ := base.([]any)
+		return n.Kind == yaml.SequenceNode && len(x) > 0 && p.patchSequence(n, b, x, ko, end)
+	}
+	return false
+}
+
+func (p *patcher) patchMapping(n *yaml.Node, base, m map[string]any, ko *keyOrder, end int) bool {
+	for i := 0; i < len(n.Content); i += 2 {
+		if !p.prefixed(p.offset(n.Content[i]), " -") {
+			return false
+		}
+	}
+	inSource := map[string]bool{}
+	last := 0
+	for i := 0; i < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		inSource[key.Value] = true
+		start, extent := p.offset(key), end
+		if i+2 < len(n.Content) {
+			extent = p.lineStart(p.offset(n.Content[i+2]))
+		}
+		cend := p.contentEnd(start, key.Column, extent)
+		last = cend
+		bv, bok := base[key.Value]
+		nv, ok := m[key.Value]
+		switch {
+		case !ok && !bok:
+			// Not represented in the parsed document, eg. a false
+			// valued field, or the merge key <<.
+			continue
+		case !ok:
+			p.loseAnchors(val)
+			if p.prefixed(start, " ") {
+				start = p.lineStart(start)
+			}
+			p.add(start, cend, "")
+		case !p.patch(val, bv, nv, ko.value(key.Value), extent):
+			p.loseAnchors(val)
+			text, err := p.entry(key, val, nv, ko.value(key.Value), key.Column)
+			if err != nil {
+				return p.fail(err)
+			}
+			p.add(start, cend, text+"\n")
+		}
+	}
+	added := map[string]any{}
+	for k, v := range m {
+		if bv, ok := base[k]; !inSource[k] && (!ok || !reflect.DeepEqual(bv, v)) {
+			added[k] = v
+		}
+	}
+	indent := strings.Repeat(" ", n.Column-1)
+	for _, k := range orderKeys(ko, added) {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+		text, err := p.entry(key, nil, added[k], ko.value(k), n.Column)
+		if err != nil {
+			return p.fail(err)
+		}
+		p.add(last, last, indent+text+"\n")
+	}
+	return true
+}
+
+func (p *patcher) patchSequence(n *yaml.Node, base, l []any, ko *keyOrder, end int) bool {
+	if len(base) != len(n.Content) {
+		return false
+	}
+	for _, item := range n.Content {
+		if !p.prefixed(p.offset(item), " -") {
+			return false
+		}
+	}
+	col := n.Content[0].Column
+	dash := bytes.LastIndexByte(p.data[:p.offset(n.Content[0])], '-')
+	dashCol := col - (p.offset(n.Content[0]) - dash)
+	last := 0
+	for i, item := range n.Content {
+		start, extent := p.offset(item), end
+		if i+1 < len(n.Content) {
+			extent = p.lineStart(p.offset(n.Content[i+1]))
+		}
+		cend := p.contentEnd(start, dashCol, extent)
+		last = cend
+		if i >= len(l) {
+			p.loseAnchors(item)
+			p.add(p.lineStart(start), cend, "")
+			continue
+		}
+		if !p.patch(item, base[i], l[i], ko.elem(), extent) {
+			p.loseAnchors(item)
+			text, err := p.value(item, l[i], ko.elem(), item.Column)
+			if err != nil {
+				return p.fail(err)
+			}
+			p.add(start, cend, text+"\n")
+		}
+	}
+	indent := strings.Repeat(" ", dashCol-1) + "- "
+	for _, v := range l[len(n.Content):] {
+		text, err := p.value(nil, v, ko.elem(), dashCol+2)
+		if err != nil {
+			return p.fail(err)
+		}
+		p.add(last, last, indent+text+"\n")
+	}
+	return true
+}
+
+// hasLostAlias returns true if n is, or contains, an alias of a node
+// whose anchor is lost.
+func (p *patcher) hasLostAlias(n *yaml.Node) bool {
+	if len(p.lost) == 0 {
+		return false
+	}
+	if n.Kind == yaml.AliasNode {
+		return p.lost[n.Alias]
+	}
+	for _, c := range n.Content {
+		if p.hasLostAlias(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// loseAnchors records that any anchors defined within n are lost.
+func (p *patcher) loseAnchors(n *yaml.Node) {
+	if len(n.Anchor) > 0 {
+		p.lost[n] = true
+	}
+	for _, c := range n.Content {
+		p.loseAnchors(c)
+	}
+}
+
+// entry returns the text for a map entry, starting at column col, with
+// the supplied key and value, it replaces the existing value prev, if any.
+func (p *patcher) entry(key, prev *yaml.Node, v any, ko *keyOrder, col int) (string, error) {
+	k := *key
+	k.HeadComment, k.LineComment, k.FootComment = "", "", ""
+	return p.encode(&yaml.Node{
+		Kind:    yaml.MappingNode,
+		Tag:     "!!map",
+		Content: []*yaml.Node{&k, p.newNode(prev, v, ko)},
+	}, col)
+}
+
+// value returns the text for v starting at column col.
+func (p *patcher) value(prev *yaml.Node, v any, ko *keyOrder, col int) (string, error) {
+	return p.encode(p.newNode(prev, v, ko), col)
+}
+
+// newNode returns a node for v that retains any line comment of the
+// scalar, or the flow style of the collection, that it replaces.
+func (p *patcher) newNode(prev *yaml.Node, v any, ko *keyOrder) *yaml.Node {
+	n := orderedNode(v, ko)
+	if p.literal {
+		setLiteralStyle(n)
+	}
+	quoteIndicatedBlocks(n)
+	if prev == nil || prev.Kind != n.Kind {
+		return n
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		n.LineComment = prev.LineComment
+	case yaml.MappingNode, yaml.SequenceNode:
+		n.Style = prev.Style & yaml.FlowStyle
+	}
+	return n
+}
+
+// encode returns the YAML text for n with all lines but the first
+// indented to start at column col.
+func (p *patcher) encode(n *yaml.Node, col int) (string, error) {
+	out := &strings.Builder{}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(p.indent)
+	if err := enc.Encode(n); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	text := strings.TrimSuffix(out.String(), "\n")
+	return strings.ReplaceAll(text, "\n", "\n"+strings.Repeat(" ", col-1)), nil
+}
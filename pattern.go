@@ -0,0 +1,106 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern represents a pattern that can be matched against the paths
+// reported by a walker. Each element of a pattern matches a single
+// element of a path literally, except for the following:
+//
+//	"*"          matches any single element.
+//	"**"         matches zero or more elements.
+//	"re:<expr>"  matches a single element against the regular expression
+//	             <expr>, which must match the entire element.
+//	"lit:<text>" matches a single element that is exactly <text>, it is
+//	             used for elements that would otherwise be treated as one
+//	             of the above, eg. "lit:*" matches a key named "*".
+//
+// For example, the pattern [components, schemas, *, properties, webURL]
+// matches the webURL property of every schema.
+type Pattern struct {
+	src   []string
+	elems []patternElem
+}
+
+type patternElem struct {
+	literal string
+	any     bool
+	anyPath bool
+	re      *regexp.Regexp
+}
+
+func (e patternElem) match(p string) bool {
+	switch {
+	case e.any:
+		return true
+	case e.re != nil:
+		return e.re.MatchString(p)
+	}
+	return e.literal == p
+}
+
+// NewPattern returns a Pattern for the supplied elements.
+func NewPattern(elems ...string) (Pattern, error) {
+	p := Pattern{src: elems, elems: make([]patternElem, len(elems))}
+	for i, e := range elems {
+		switch {
+		case e == "*":
+			p.elems[i].any = true
+		case e == "**":
+			p.elems[i].anyPath = true
+		case strings.HasPrefix(e, "lit:"):
+			p.elems[i].literal = strings.TrimPrefix(e, "lit:")
+		case strings.HasPrefix(e, "re:"):
+			re, err := regexp.Compile("^(?:" + strings.TrimPrefix(e, "re:") + ")$")
+			if err != nil {
+				return Pattern{}, fmt.Errorf("pattern element %v: %v", i, err)
+			}
+			p.elems[i].re = re
+		default:
+			p.elems[i].literal = e
+		}
+	}
+	return p, nil
+}
+
+// Match returns true if the pattern matches the entire path.
+func (p Pattern) Match(path []string) bool {
+	return matchElems(p.elems, path, false)
+}
+
+// MatchPrefix returns true if the pattern matches a prefix of path, ie.
+// path is the node matched by the pattern or one of its descendants.
+// An empty pattern matches all paths.
+func (p Pattern) MatchPrefix(path []string) bool {
+	return matchElems(p.elems, path, true)
+}
+
+func matchElems(elems []patternElem, path []string, prefix bool) bool {
+	for i, e := range elems {
+		if e.anyPath {
+			for j := 0; j <= len(path); j++ {
+				if matchElems(elems[i+1:], path[j:], prefix) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || !e.match(path[0]) {
+			return false
+		}
+		path = path[1:]
+	}
+	return prefix || len(path) == 0
+}
+
+// String implements fmt.Stringer.
+func (p Pattern) String() string {
+	return "[" + strings.Join(p.src, ", ") + "]"
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+)
+
+func TestPattern(t *testing.T) {
+	type elems []string
+	for i, tc := range []struct {
+		pattern, path elems
+		match, there  bool
+	}{
+		{nil, nil, true, true},
+		{nil, elems{"a", "b"}, false, true},
+		{elems{"a", "b"}, elems{"a", "b"}, true, true},
+		{elems{"a", "b"}, elems{"a", "b", "c"}, false, true},
+		{elems{"a", "b"}, elems{"a"}, false, false},
+		{elems{"a", "b"}, elems{"a", "c"}, false, false},
+		{elems{"a", "*", "c"}, elems{"a", "b", "c"}, true, true},
+		{elems{"a", "*", "c"}, elems{"a", "c"}, false, false},
+		{elems{"a", "*"}, elems{"a", "b", "c"}, false, true},
+		{elems{"**"}, nil, true, true},
+		{elems{"**"}, elems{"a", "b", "c"}, true, true},
+		{elems{"a", "**"}, elems{"a"}, true, true},
+		{elems{"a", "**", "c"}, elems{"a", "c"}, true, true},
+		{elems{"a", "**", "c"}, elems{"a", "b", "x", "c"}, true, true},
+		{elems{"a", "**", "c"}, elems{"a", "b", "x", "c", "d"}, false, true},
+		{elems{"a", "**", "c"}, elems{"a", "b", "x", "d"}, false, false},
+		{elems{"**", "webURL"}, elems{"components", "schemas", "A", "properties", "webURL"}, true, true},
+		{elems{"**", "properties", "*", "format"}, elems{"x", "properties", "y", "format"}, true, true},
+		{elems{"a", "re:b+", "c"}, elems{"a", "bbb", "c"}, true, true},
+		{elems{"a", "re:b+", "c"}, elems{"a", "bbbx", "c"}, false, false},
+		{elems{"a", "re:(get|put)"}, elems{"a", "put"}, true, true},
+		{elems{"a", "re:(get|put)"}, elems{"a", "post"}, false, false},
+		{elems{"a", "re:x:y"}, elems{"a", "x:y"}, true, true},
+		{elems{"a", "lit:*"}, elems{"a", "*"}, true, true},
+		{elems{"a", "lit:*"}, elems{"a", "b"}, false, false},
+		{elems{"lit:**", "b"}, elems{"**", "b"}, true, true},
+		{elems{"lit:**", "b"}, elems{"a", "b"}, false, false},
+		{elems{"lit:re:b+"}, elems{"re:b+"}, true, true},
+		{elems{"lit:re:b+"}, elems{"bb"}, false, false},
+		{elems{"lit:lit:a"}, elems{"lit:a"}, true, true},
+	} {
+		p, err := openapi.NewPattern(tc.pattern...)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if got, want := p.Match(tc.path), tc.match; got != want {
+			t.Errorf("%v: %v.Match(%v): got %v, want %v", i, p, tc.path, got, want)
+		}
+		if got, want := p.MatchPrefix(tc.path), tc.there; got != want {
+			t.Errorf("%v: %v.MatchPrefix(%v): got %v, want %v", i, p, tc.path, got, want)
+		}
+	}
+
+	if _, err := openapi.NewPattern("a", "re:(", "b"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// JSONPointer returns the RFC 6901 JSON Pointer representation of the
+// supplied walker path, eg. [paths, /pets, get] is returned as
+// /paths/~1pets/get. Prefix the result with # to use it in a $ref.
+func JSONPointer(path []string) string {
+	out := &strings.Builder{}
+	for _, p := range path {
+		out.WriteRune('/')
+		out.WriteString(pointerEscaper.Replace(p))
+	}
+	return out.String()
+}
+
+// ParseJSONPointer returns the walker path represented by the supplied
+// RFC 6901 JSON Pointer. The pointer may also be in URI fragment form,
+// ie. #/paths/~1pets, as used in $refs.
+func ParseJSONPointer(ptr string) ([]string, error) {
+	if strings.HasPrefix(ptr, "#") {
+		unescaped, err := url.PathUnescape(ptr[1:])
+		if err != nil {
+			return nil, err
+		}
+		ptr = unescaped
+	}
+	if len(ptr) == 0 {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("%q: json pointer does not start with /", ptr)
+	}
+	parts := strings.Split(ptr[1:], "/")
+	for i, p := range parts {
+		for j := 0; j < len(p); j++ {
+			if p[j] == '~' && (j+1 == len(p) || (p[j+1] != '0' && p[j+1] != '1')) {
+				return nil, fmt.Errorf("%q: invalid escape sequence in %q", ptr, p)
+			}
+		}
+		parts[i] = pointerUnescaper.Replace(p)
+	}
+	return parts, nil
+}
+
+// PathError records an error returned by a Visitor along with the path
+// of the node that it was returned for and, if a Source was specified
+// for the walk via WalkerSource, its position in that source.
+type PathError struct {
+	Path []string
+	Pos  Position
+	Err  error
+	// formatted is the path as formatted by the walker, see
+	// WalkerJSONPointers.
+	formatted string
+}
+
+// Error implements error. The path is formatted as a JSON Pointer if
+// WalkerJSONPointers was specified for the walk and is preceded by the
+// position of the node if it is known.
+func (e *PathError) Error() string {
+	path := e.formatted
+	if len(path) == 0 {
+		path = strings.Join(e.Path, ":")
+	}
+	msg := path + ": " + e.Err.Error()
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap implements errors.Unwrap.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// formatPath returns path, the path of node, as it is to be displayed in
+// traces and errors. If WalkerJSONPointers or WalkerReportJSONPointers
+// was specified it is returned as a JSON Pointer to the node's
+// definition, see CanonicalPath, so that it resolves against the document
+// even for nodes reached via a $ref.
+func (st *walkState) formatPath(path []string, node any) string {
+	switch {
+	case st.pointers:
+		return JSONPointer(CanonicalPath(st.refs, path))
+	case st.reportPtrs:
+		refs := make([]Ref, len(st.refs))
+		for i, r := range st.refs {
+			refs[i] = Ref{Ref: r.Ref, Path: st.documentPath(r.Path, nil)}
+		}
+		return JSONPointer(CanonicalPath(refs, st.documentPath(path, node)))
+	}
+	return strings.Join(path, ":")
+}
+
+// documentPath returns path, the path of node, as it would be if
+// WalkerJSONPointers were specified, that is, with parameters identified
+// by their index and without the "extensions" element that precedes the
+// names of extensions. The ancestors of node are used to determine which
+// elements of path are parameters and extensions. Node may be nil if
+// it is itself the innermost ancestor.
+func (st *walkState) documentPath(path []string, node any) []string {
+	// nodes[i] is the outermost node at path[:i], if known.
+	nodes := make([]any, len(path)+1)
+	for _, a := range st.ancestors {
+		if n := len(a.Path); n <= len(path) && nodes[n] == nil {
+			nodes[n] = a.Node
+		}
+	}
+	if nodes[len(path)] == nil {
+		nodes[len(path)] = node
+	}
+	dp := make([]string, 0, len(path))
+	for i, p := range path {
+		switch pars := nodes[i].(type) {
+		case *openapi3.Parameters:
+			p = elemIndex(*pars, nodes[i+1], p)
+		case *openapi2.Parameters:
+			p = elemIndex(*pars, nodes[i+1], p)
+		}
+		if _, ok := nodes[i+1].(*map[string]any); ok && p == "extensions" {
+			continue
+		}
+		dp = append(dp, p)
+	}
+	return dp
+}
+
+// elemIndex returns the index of elem within list, or name if it is not
+// in the list.
+func elemIndex[E comparable](list []E, elem any, name string) string {
+	for i, e := range list {
+		if any(e) == elem {
+			return strconv.Itoa(i)
+		}
+	}
+	return name
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestJSONPointer(t *testing.T) {
+	for i, tc := range []struct {
+		path    []string
+		pointer string
+	}{
+		{nil, ""},
+		{[]string{"components", "schemas", "Pet"}, "/components/schemas/Pet"},
+		{[]string{"paths", "/pets/{id}", "get"}, "/paths/~1pets~1{id}/get"},
+		{[]string{"a~b", "c~/d"}, "/a~0b/c~0~1d"},
+		{[]string{"", "x"}, "//x"},
+	} {
+		if got, want := openapi.JSONPointer(tc.path), tc.pointer; got != want {
+			t.Errorf("%v: got %q, want %q", i, got, want)
+		}
+		path, err := openapi.ParseJSONPointer(tc.pointer)
+		if err != nil {
+			t.Errorf("%v: %v", i, err)
+			continue
+		}
+		if got, want := path, tc.path; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %q, want %q", i, got, want)
+		}
+	}
+
+	path, err := openapi.ParseJSONPointer("#/paths/~1pets~1%7Bid%7D/get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := path, []string{"paths", "/pets/{id}", "get"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, ptr := range []string{"a/b", "/a~2", "/a~", "#/a%zz"} {
+		if _, err := openapi.ParseJSONPointer(ptr); err == nil {
+			t.Errorf("%q: expected an error", ptr)
+		}
+	}
+}
+
+// resolve returns the value in doc, decoded from JSON, that ptr refers to.
+func resolve(doc any, ptr string) (any, bool) {
+	path, err := openapi.ParseJSONPointer(ptr)
+	if err != nil {
+		return nil, false
+	}
+	v := doc
+	for _, p := range path {
+		switch x := v.(type) {
+		case map[string]any:
+			e, ok := x[p]
+			if !ok {
+				return nil, false
+			}
+			v = e
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func asJSON(t *testing.T, v any) any {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r any
+	if err := json.Unmarshal(buf, &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWalkerJSONPointers(t *testing.T) {
+	ctx := context.Background()
+	for _, filename := range []string{"kitchensink.yaml", "openapi31.yaml", "petstore-expanded.yaml"} {
+		for _, all := range []bool{false, true} {
+			doc := loadYAML(filename)
+			// Decoding 3.1 keywords drops empty values, eg. required: [],
+			// so compare against the decoded document.
+			if err := openapi.Decode31(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+			if filename == "kitchensink.yaml" {
+				doc.Components.Schemas["Pet"].Value.Discriminator.Extensions = map[string]any{"x-kind": "pet"}
+			}
+			root := asJSON(t, doc)
+			visited := map[string]bool{}
+			err := openapi.NewContextWalker(func(ctx context.Context, path []string, parent, node any) (bool, error) {
+				if _, ok := node.(openapi3.AdditionalProperties); ok {
+					// Visited with the same path as its schema.
+					return true, nil
+				}
+				ptr := openapi.JSONPointer(openapi.CanonicalPath(openapi.RefChain(ctx), path))
+				if ptr == "/externalDocs" && doc.ExternalDocs == nil {
+					// The document's externalDocs is visited even when
+					// it is missing.
+					return true, nil
+				}
+				visited[ptr] = true
+				v, ok := resolve(root, ptr)
+				if !ok {
+					t.Errorf("%v: all fields %v: %v does not resolve", filename, all, ptr)
+					return true, nil
+				}
+				if got, want := asJSON(t, node), v; !reflect.DeepEqual(got, want) {
+					t.Errorf("%v: all fields %v: %v: got %v, want %v", filename, all, ptr, got, want)
+				}
+				return true, nil
+			}, openapi.WalkerJSONPointers(true), openapi.WalkerAllFields(all)).WalkContext(ctx, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(visited) == 0 {
+				t.Errorf("%v: no nodes visited", filename)
+			}
+			if filename == "kitchensink.yaml" && !visited["/components/schemas/Pet/discriminator/x-kind"] {
+				t.Errorf("all fields %v: discriminator extension not visited", all)
+			}
+		}
+	}
+}
+
+func TestWalkerReportJSONPointers(t *testing.T) {
+	// The paths traced with WalkerReportJSONPointers must resolve against
+	// the document, whereas those passed to visitors are unchanged.
+	ctx := context.Background()
+	for _, filename := range []string{"kitchensink.yaml", "petstore-expanded.yaml"} {
+		for _, all := range []bool{false, true} {
+			doc := loadYAML(filename)
+			if err := openapi.Decode31(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+			if filename == "kitchensink.yaml" {
+				doc.Components.Schemas["Pet"].Value.Discriminator.Extensions = map[string]any{"x-kind": "pet"}
+			}
+			root := asJSON(t, doc)
+			var paths []string
+			var nodes []any
+			out := &strings.Builder{}
+			err := openapi.NewWalker(func(path []string, parent, node any) (bool, error) {
+				paths = append(paths, strings.Join(path, ":"))
+				nodes = append(nodes, node)
+				return true, nil
+			}, openapi.WalkerReportJSONPointers(true), openapi.WalkerAllFields(all),
+				openapi.WalkerTracePaths(true), openapi.WalkerTraceWriter(out)).Walk(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ptrs := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if got, want := len(ptrs), len(nodes); got != want {
+				t.Fatalf("%v: all fields %v: got %v traces, want %v", filename, all, got, want)
+			}
+			for i, ptr := range ptrs {
+				switch nodes[i].(type) {
+				case openapi3.AdditionalProperties, *map[string]any:
+					// Visited with the same path as their schema or
+					// the node they extend.
+					continue
+				}
+				if ptr == "/externalDocs" && doc.ExternalDocs == nil {
+					continue
+				}
+				v, ok := resolve(root, ptr)
+				if !ok {
+					t.Errorf("%v: all fields %v: %v: %v does not resolve", filename, all, paths[i], ptr)
+					continue
+				}
+				if got, want := asJSON(t, nodes[i]), v; !reflect.DeepEqual(got, want) {
+					t.Errorf("%v: all fields %v: %v: got %v, want %v", filename, all, ptr, got, want)
+				}
+			}
+			if filename != "kitchensink.yaml" {
+				continue
+			}
+			for path, ptr := range map[string]string{
+				"paths:/pets/{id}:get:parameters:limit":                  "/paths/~1pets~1{id}/get/parameters/0",
+				"components:schemas:Pet:discriminator:extensions:x-kind": "/components/schemas/Pet/discriminator/x-kind",
+			} {
+				i := 0
+				for i < len(paths) && paths[i] != path {
+					i++
+				}
+				if i == len(paths) {
+					t.Errorf("all fields %v: %v: not visited", all, path)
+				} else if got, want := ptrs[i], ptr; got != want {
+					t.Errorf("all fields %v: %v: got %v, want %v", all, path, got, want)
+				}
+			}
+		}
+	}
+}
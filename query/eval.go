@@ -0,0 +1,286 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"reflect"
+	"unicode/utf8"
+)
+
+type evaluator struct {
+	root *node
+}
+
+func (e *evaluator) query(q *queryExpr, current *node) []*node {
+	start := e.root
+	if q.relative {
+		start = current
+	}
+	nodes := []*node{start}
+	for _, seg := range q.segments {
+		var next []*node
+		for _, n := range nodes {
+			if seg.descendant {
+				descendants(n, func(d *node) {
+					next = e.selectors(next, d, seg.selectors)
+				})
+				continue
+			}
+			next = e.selectors(next, n, seg.selectors)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// descendants calls fn for n and all of its descendants in document
+// order.
+func descendants(n *node, fn func(*node)) {
+	fn(n)
+	for _, c := range n.children {
+		descendants(c, fn)
+	}
+}
+
+func (e *evaluator) selectors(out []*node, n *node, sels []selector) []*node {
+	for _, sel := range sels {
+		switch s := sel.(type) {
+		case nameSelector:
+			// Names are also used to select the elements of arrays,
+			// such as parameters, that are identified by name.
+			if c, ok := n.byName[s.name]; ok {
+				out = append(out, c)
+			}
+		case wildcardSelector:
+			out = append(out, n.children...)
+		case indexSelector:
+			if !n.array {
+				continue
+			}
+			i := s.index
+			if i < 0 {
+				i += len(n.children)
+			}
+			if i >= 0 && i < len(n.children) {
+				out = append(out, n.children[i])
+			}
+		case sliceSelector:
+			if n.array {
+				out = slice(out, n.children, s)
+			}
+		case filterSelector:
+			for _, c := range n.children {
+				if e.logical(s.expr, c) {
+					out = append(out, c)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func normalizeIndex(i, length int) int {
+	if i < 0 {
+		return length + i
+	}
+	return i
+}
+
+func bound(i, lower, upper int) int {
+	if i < lower {
+		return lower
+	}
+	if i > upper {
+		return upper
+	}
+	return i
+}
+
+// slice implements the array slice selector as defined by RFC 9535.
+func slice(out, children []*node, s sliceSelector) []*node {
+	n := len(children)
+	if s.step == 0 {
+		return out
+	}
+	var start, end int
+	if s.step > 0 {
+		start, end = 0, n
+	} else {
+		start, end = n-1, -n-1
+	}
+	if s.start != nil {
+		start = normalizeIndex(*s.start, n)
+	}
+	if s.end != nil {
+		end = normalizeIndex(*s.end, n)
+	}
+	if s.step > 0 {
+		for i := bound(start, 0, n); i < bound(end, 0, n); i += s.step {
+			out = append(out, children[i])
+		}
+		return out
+	}
+	for i := bound(start, -1, n-1); i > bound(end, -1, n-1); i += s.step {
+		out = append(out, children[i])
+	}
+	return out
+}
+
+func (e *evaluator) logical(expr logicalExpr, current *node) bool {
+	switch x := expr.(type) {
+	case orExpr:
+		for _, sub := range x {
+			if e.logical(sub, current) {
+				return true
+			}
+		}
+		return false
+	case andExpr:
+		for _, sub := range x {
+			if !e.logical(sub, current) {
+				return false
+			}
+		}
+		return true
+	case notExpr:
+		return !e.logical(x.expr, current)
+	case compExpr:
+		return compare(x.op, e.value(x.left, current), e.value(x.right, current))
+	case testExpr:
+		switch o := x.operand.(type) {
+		case *queryExpr:
+			return len(e.query(o, current)) > 0
+		case *funcExpr:
+			b, _ := e.call(o, current).value.(bool)
+			return b
+		}
+	}
+	return false
+}
+
+// operand represents the value of a comparable, it is either a node,
+// a value, or nothing.
+type operand struct {
+	node    *node
+	value   any
+	nothing bool
+}
+
+func (o operand) json() any {
+	if o.node != nil {
+		return o.node.json()
+	}
+	return o.value
+}
+
+var nothing = operand{nothing: true}
+
+func (e *evaluator) value(c comparable, current *node) operand {
+	switch x := c.(type) {
+	case literal:
+		return operand{value: x.value}
+	case *queryExpr:
+		nodes := e.query(x, current)
+		if len(nodes) != 1 {
+			return nothing
+		}
+		return operand{node: nodes[0]}
+	case *funcExpr:
+		return e.call(x, current)
+	}
+	return nothing
+}
+
+func (e *evaluator) call(fe *funcExpr, current *node) operand {
+	switch fe.name {
+	case "length":
+		arg := e.value(fe.args[0], current)
+		switch {
+		case arg.nothing:
+			return nothing
+		case arg.node != nil:
+			if s, ok := arg.node.scalar(); ok {
+				return length(s)
+			}
+			return operand{value: float64(len(arg.node.children))}
+		}
+		return length(arg.value)
+	case "count":
+		return operand{value: float64(len(e.query(fe.args[0].(*queryExpr), current)))}
+	case "value":
+		nodes := e.query(fe.args[0].(*queryExpr), current)
+		if len(nodes) != 1 {
+			return nothing
+		}
+		return operand{node: nodes[0]}
+	case "match", "search":
+		s, ok := e.value(fe.args[0], current).json().(string)
+		if !ok {
+			return operand{value: false}
+		}
+		re := fe.re
+		if re == nil {
+			var err error
+			re, err = compileRegexp(fe.name, e.value(fe.args[1], current).json())
+			if err != nil || re == nil {
+				return operand{value: false}
+			}
+		}
+		return operand{value: re.MatchString(s)}
+	}
+	return nothing
+}
+
+func length(v any) operand {
+	switch x := v.(type) {
+	case string:
+		return operand{value: float64(utf8.RuneCountInString(x))}
+	case []any:
+		return operand{value: float64(len(x))}
+	case map[string]any:
+		return operand{value: float64(len(x))}
+	}
+	return nothing
+}
+
+func compare(op string, a, b operand) bool {
+	switch op {
+	case "==":
+		return equal(a, b)
+	case "!=":
+		return !equal(a, b)
+	case "<":
+		return less(a, b)
+	case ">":
+		return less(b, a)
+	case "<=":
+		return less(a, b) || equal(a, b)
+	case ">=":
+		return less(b, a) || equal(a, b)
+	}
+	return false
+}
+
+func equal(a, b operand) bool {
+	if a.nothing || b.nothing {
+		return a.nothing && b.nothing
+	}
+	return reflect.DeepEqual(a.json(), b.json())
+}
+
+func less(a, b operand) bool {
+	if a.nothing || b.nothing {
+		return false
+	}
+	switch x := a.json().(type) {
+	case float64:
+		y, ok := b.json().(float64)
+		return ok && x < y
+	case string:
+		y, ok := b.json().(string)
+		return ok && x < y
+	}
+	return false
+}
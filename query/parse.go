@@ -0,0 +1,717 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// segment represents a child, or descendant, segment of a query.
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+// selector is implemented by nameSelector, wildcardSelector,
+// indexSelector, sliceSelector and filterSelector.
+type selector interface{}
+
+type nameSelector struct {
+	name string
+}
+
+type wildcardSelector struct{}
+
+type indexSelector struct {
+	index int
+}
+
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+type filterSelector struct {
+	expr logicalExpr
+}
+
+// logicalExpr is implemented by orExpr, andExpr, notExpr, compExpr
+// and testExpr.
+type logicalExpr interface{}
+
+type orExpr []logicalExpr
+
+type andExpr []logicalExpr
+
+type notExpr struct {
+	expr logicalExpr
+}
+
+type compExpr struct {
+	op          string
+	left, right comparable
+}
+
+// testExpr is either an existence test for a query or a call to a
+// function that returns a logical value.
+type testExpr struct {
+	operand comparable
+}
+
+// comparable is implemented by literal, *queryExpr and *funcExpr.
+type comparable interface{}
+
+type literal struct {
+	value any
+}
+
+type queryExpr struct {
+	relative bool
+	segments []segment
+}
+
+// singular returns true if q can select at most one node.
+func (q *queryExpr) singular() bool {
+	for _, s := range q.segments {
+		if s.descendant || len(s.selectors) != 1 {
+			return false
+		}
+		switch s.selectors[0].(type) {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type funcExpr struct {
+	name string
+	fn   function
+	args []comparable
+	// re is the compiled regular expression for match and search when
+	// the pattern is a literal.
+	re *regexp.Regexp
+}
+
+type paramType int
+
+const (
+	valueType paramType = iota
+	nodesType
+	logicalType
+)
+
+type function struct {
+	params []paramType
+	result paramType
+}
+
+var functions = map[string]function{
+	"length": {params: []paramType{valueType}, result: valueType},
+	"count":  {params: []paramType{nodesType}, result: valueType},
+	"match":  {params: []paramType{valueType, valueType}, result: logicalType},
+	"search": {params: []paramType{valueType, valueType}, result: logicalType},
+	"value":  {params: []paramType{nodesType}, result: valueType},
+}
+
+const maxInt = 1<<53 - 1
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("query %q: offset %v: %v", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+			continue
+		}
+		return
+	}
+}
+
+func (p *parser) expect(s string) error {
+	if !p.hasPrefix(s) {
+		return p.errorf("expected %q", s)
+	}
+	p.pos += len(s)
+	return nil
+}
+
+func parse(src string) (*queryExpr, error) {
+	p := &parser{src: src}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	segs, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return &queryExpr{segments: segs}, nil
+}
+
+func (p *parser) segments() ([]segment, error) {
+	var segs []segment
+	for {
+		start := p.pos
+		p.skipSpace()
+		if p.peek() != '[' && p.peek() != '.' {
+			p.pos = start
+			return segs, nil
+		}
+		seg, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+}
+
+func (p *parser) segment() (segment, error) {
+	switch {
+	case p.hasPrefix(".."):
+		p.pos += 2
+		seg := segment{descendant: true}
+		switch {
+		case p.peek() == '[':
+			sels, err := p.bracketed()
+			if err != nil {
+				return seg, err
+			}
+			seg.selectors = sels
+		default:
+			sel, err := p.shorthand()
+			if err != nil {
+				return seg, err
+			}
+			seg.selectors = []selector{sel}
+		}
+		return seg, nil
+	case p.peek() == '.':
+		p.pos++
+		sel, err := p.shorthand()
+		return segment{selectors: []selector{sel}}, err
+	}
+	sels, err := p.bracketed()
+	return segment{selectors: sels}, err
+}
+
+func isNameFirst(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// shorthand parses a wildcard or member name following a '.'.
+func (p *parser) shorthand() (selector, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return wildcardSelector{}, nil
+	}
+	start := p.pos
+	for !p.eof() {
+		r, n := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !isNameFirst(r) && (p.pos == start || !isDigit(p.peek())) {
+			break
+		}
+		p.pos += n
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a member name or '*'")
+	}
+	return nameSelector{name: p.src[start:p.pos]}, nil
+}
+
+func (p *parser) bracketed() ([]selector, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var sels []selector
+	for {
+		p.skipSpace()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			continue
+		case ']':
+			p.pos++
+			return sels, nil
+		}
+		return nil, p.errorf("expected ',' or ']'")
+	}
+}
+
+func (p *parser) selector() (selector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		return nameSelector{name: s}, err
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.logicalOr()
+		return filterSelector{expr: expr}, err
+	case c == ':' || c == '-' || isDigit(c):
+		return p.indexOrSlice()
+	}
+	return nil, p.errorf("invalid selector")
+}
+
+func (p *parser) optionalInt() (*int, error) {
+	if p.peek() != '-' && !isDigit(p.peek()) {
+		return nil, nil
+	}
+	i, err := p.integer()
+	return &i, err
+}
+
+func (p *parser) integer() (int, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	digits := p.pos
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	s := p.src[start:p.pos]
+	switch {
+	case p.pos == digits:
+		return 0, p.errorf("expected an integer")
+	case p.src[digits] == '0' && (p.pos-digits > 1 || digits > start):
+		return 0, p.errorf("invalid integer %q", s)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || i > maxInt || i < -maxInt {
+		return 0, p.errorf("integer %q out of range", s)
+	}
+	return int(i), nil
+}
+
+func (p *parser) indexOrSlice() (selector, error) {
+	start, err := p.optionalInt()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != ':' {
+		if start == nil {
+			return nil, p.errorf("expected an index")
+		}
+		return indexSelector{index: *start}, nil
+	}
+	p.pos++
+	p.skipSpace()
+	sel := sliceSelector{start: start, step: 1}
+	if sel.end, err = p.optionalInt(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() == ':' {
+		p.pos++
+		p.skipSpace()
+		step, err := p.optionalInt()
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			sel.step = *step
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) stringLiteral() (string, error) {
+	quote := p.peek()
+	p.pos++
+	out := &strings.Builder{}
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		switch {
+		case c == quote:
+			p.pos++
+			return out.String(), nil
+		case c < 0x20:
+			return "", p.errorf("invalid character in string")
+		case c != '\\':
+			r, n := utf8.DecodeRuneInString(p.src[p.pos:])
+			out.WriteRune(r)
+			p.pos += n
+			continue
+		}
+		p.pos++
+		esc := p.peek()
+		p.pos++
+		switch esc {
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case '/', '\\':
+			out.WriteByte(esc)
+		case 'u':
+			r, err := p.unicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			out.WriteRune(r)
+		default:
+			if esc != quote {
+				return "", p.errorf("invalid escape \\%c", esc)
+			}
+			out.WriteByte(esc)
+		}
+	}
+}
+
+func (p *parser) hex4() (rune, error) {
+	if p.pos+4 > len(p.src) {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	v, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 16)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	p.pos += 4
+	return rune(v), nil
+}
+
+func (p *parser) unicodeEscape() (rune, error) {
+	r, err := p.hex4()
+	if err != nil || !utf16.IsSurrogate(r) {
+		return r, err
+	}
+	if !p.hasPrefix(`\u`) {
+		return 0, p.errorf("invalid surrogate pair")
+	}
+	p.pos += 2
+	r2, err := p.hex4()
+	if err != nil {
+		return 0, err
+	}
+	if d := utf16.DecodeRune(r, r2); d != utf8.RuneError {
+		return d, nil
+	}
+	return 0, p.errorf("invalid surrogate pair")
+}
+
+func (p *parser) logicalOr() (logicalExpr, error) {
+	var or orExpr
+	for {
+		and, err := p.logicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, and)
+		p.skipSpace()
+		if !p.hasPrefix("||") {
+			break
+		}
+		p.pos += 2
+		p.skipSpace()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) logicalAnd() (logicalExpr, error) {
+	var and andExpr
+	for {
+		expr, err := p.basic()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		p.skipSpace()
+		if !p.hasPrefix("&&") {
+			break
+		}
+		p.pos += 2
+		p.skipSpace()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) basic() (logicalExpr, error) {
+	if p.peek() == '!' && !p.hasPrefix("!=") {
+		p.pos++
+		p.skipSpace()
+		expr, err := p.parenOrTest()
+		return notExpr{expr: expr}, err
+	}
+	if p.peek() == '(' {
+		return p.parenOrTest()
+	}
+	left, err := p.comparable()
+	if err != nil {
+		return nil, err
+	}
+	start := p.pos
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.hasPrefix(op) {
+			continue
+		}
+		p.pos += len(op)
+		p.skipSpace()
+		right, err := p.comparable()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkComparable(left); err != nil {
+			return nil, err
+		}
+		if err := p.checkComparable(right); err != nil {
+			return nil, err
+		}
+		return compExpr{op: op, left: left, right: right}, nil
+	}
+	p.pos = start
+	return p.test(left)
+}
+
+// parenOrTest parses a parenthesized expression or a test expression.
+func (p *parser) parenOrTest() (logicalExpr, error) {
+	if p.peek() != '(' {
+		operand, err := p.comparable()
+		if err != nil {
+			return nil, err
+		}
+		return p.test(operand)
+	}
+	p.pos++
+	p.skipSpace()
+	expr, err := p.logicalOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	return expr, p.expect(")")
+}
+
+func (p *parser) test(operand comparable) (logicalExpr, error) {
+	switch o := operand.(type) {
+	case *queryExpr:
+		return testExpr{operand: o}, nil
+	case *funcExpr:
+		if o.fn.result == valueType {
+			return nil, p.errorf("the result of %v() must be compared", o.name)
+		}
+		return testExpr{operand: o}, nil
+	}
+	return nil, p.errorf("a literal cannot be used as a test")
+}
+
+func (p *parser) checkComparable(c comparable) error {
+	switch o := c.(type) {
+	case *queryExpr:
+		if !o.singular() {
+			return p.errorf("only singular queries can be compared")
+		}
+	case *funcExpr:
+		if o.fn.result != valueType {
+			return p.errorf("the result of %v() cannot be compared", o.name)
+		}
+	}
+	return nil
+}
+
+func (p *parser) comparable() (comparable, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segs, err := p.segments()
+		return &queryExpr{relative: c == '@', segments: segs}, err
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		return literal{value: s}, err
+	case c == '-' || isDigit(c):
+		return p.number()
+	case c >= 'a' && c <= 'z':
+		for _, kw := range []struct {
+			name  string
+			value any
+		}{{"true", true}, {"false", false}, {"null", nil}} {
+			if p.hasPrefix(kw.name) {
+				p.pos += len(kw.name)
+				return literal{value: kw.value}, nil
+			}
+		}
+		return p.function()
+	}
+	return nil, p.errorf("expected a literal, query or function")
+}
+
+func (p *parser) number() (comparable, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	digits := p.pos
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	if p.pos == digits || (p.src[digits] == '0' && p.pos-digits > 1) {
+		return nil, p.errorf("invalid number")
+	}
+	if p.peek() == '.' {
+		p.pos++
+		frac := p.pos
+		for isDigit(p.peek()) {
+			p.pos++
+		}
+		if p.pos == frac {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		p.pos++
+		if c := p.peek(); c == '+' || c == '-' {
+			p.pos++
+		}
+		exp := p.pos
+		for isDigit(p.peek()) {
+			p.pos++
+		}
+		if p.pos == exp {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil || math.IsInf(f, 0) {
+		return nil, p.errorf("invalid number")
+	}
+	return literal{value: f}, nil
+}
+
+func (p *parser) function() (comparable, error) {
+	start := p.pos
+	for c := p.peek(); (c >= 'a' && c <= 'z') || isDigit(c) || c == '_'; c = p.peek() {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	fn, ok := functions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %q", name)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	fe := &funcExpr{name: name, fn: fn}
+	p.skipSpace()
+	for p.peek() != ')' {
+		arg, err := p.comparable()
+		if err != nil {
+			return nil, err
+		}
+		fe.args = append(fe.args, arg)
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.pos++
+			p.skipSpace()
+		}
+	}
+	p.pos++
+	if len(fe.args) != len(fn.params) {
+		return nil, p.errorf("%v() requires %v arguments", name, len(fn.params))
+	}
+	for i, arg := range fe.args {
+		if err := p.checkArg(fe, fn.params[i], arg); err != nil {
+			return nil, err
+		}
+	}
+	if name == "match" || name == "search" {
+		if l, ok := fe.args[1].(literal); ok {
+			re, err := compileRegexp(name, l.value)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			fe.re = re
+		}
+	}
+	return fe, nil
+}
+
+func (p *parser) checkArg(fe *funcExpr, pt paramType, arg comparable) error {
+	switch pt {
+	case valueType:
+		return p.checkComparable(arg)
+	case nodesType:
+		if _, ok := arg.(*queryExpr); !ok {
+			return p.errorf("the argument to %v() must be a query", fe.name)
+		}
+	}
+	return nil
+}
+
+// compileRegexp compiles the pattern used by match, which must match
+// an entire string, or search, which may match any part of one.
+func compileRegexp(fn string, pattern any) (*regexp.Regexp, error) {
+	s, ok := pattern.(string)
+	if !ok {
+		return nil, nil
+	}
+	if fn == "match" {
+		s = "^(?:" + s + ")$"
+	}
+	return regexp.Compile(s)
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package query provides support for selecting nodes in an openapi3
+// document using JSONPath (RFC 9535) expressions. For example:
+//
+//	$..properties[?@.type == 'string' && @.format == 'uri']
+//	$.paths.*[?@.tags[?@ == 'beta']]
+//	$.components.schemas[?length(@.required) > 2]
+//
+// Queries are evaluated against the document as it is seen by a walker
+// created with openapi.WalkerAllFields, together with the scalar valued
+// fields, such as type and format, of the nodes that it visits. The
+// member names used in queries are therefore the elements of the paths
+// reported by the walker, with the following consequences:
+//
+//   - extensions appear as members of an "extensions" object, eg.
+//     $.info.extensions['x-logo'].
+//   - the elements of parameter lists may be selected by name, eg.
+//     $.paths['/pets'].get.parameters.limit, as well as by index.
+//   - if openapi.WalkerJSONPointers is specified, extensions appear as
+//     members of the objects that they extend, eg. $.info['x-logo'], and
+//     parameters may only be selected by index, as they are in the
+//     document itself.
+//   - $refs that are not followed appear as objects with a single
+//     "$ref" member, and the nodes referred to by those that are followed
+//     appear in their place.
+//
+// All of the standard functions, length, count, match, search and value
+// are supported. Regular expressions are evaluated using Go's regexp
+// package rather than as I-Regexps.
+package query
+
+import (
+	"context"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Query represents a parsed JSONPath expression.
+type Query struct {
+	src  string
+	expr *queryExpr
+}
+
+// Match represents a node selected by a query.
+type Match struct {
+	// Path is the path of the node as reported by the walker.
+	Path []string
+	// Parent is the node that contains the node, or nil for the
+	// document itself.
+	Parent any
+	// Node is the selected node. Nodes are passed by reference wherever
+	// possible, so that for example a string valued field such as a
+	// schema's format is returned as a *string.
+	Node any
+}
+
+// Parse parses a JSONPath expression.
+func Parse(expr string) (*Query, error) {
+	q, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{src: expr, expr: q}, nil
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(expr string) *Query {
+	q, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String implements fmt.Stringer.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Select returns the nodes in doc selected by the query in document
+// order. The options are passed to the walker used to traverse doc, for
+// example WalkerFollowRefs may be used to follow $refs. Nodes are visited
+// in sorted order unless WalkerOrder is specified.
+func (q *Query) Select(ctx context.Context, doc *openapi3.T, opts ...openapi.WalkerOption) ([]Match, error) {
+	root, err := buildTree(ctx, doc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	e := &evaluator{root: root}
+	nodes := e.query(q.expr, root)
+	matches := make([]Match, len(nodes))
+	for i, n := range nodes {
+		matches[i] = Match{Path: n.path, Node: n.value}
+		if n.parent != nil {
+			matches[i].Parent = n.parent.value
+		}
+	}
+	return matches, nil
+}
+
+// Select parses expr and returns the nodes in doc that it selects,
+// see Query.Select.
+func Select(ctx context.Context, doc *openapi3.T, expr string, opts ...openapi.WalkerOption) ([]Match, error) {
+	q, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(ctx, doc, opts...)
+}
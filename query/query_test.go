@@ -0,0 +1,230 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package query_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/cosnicolaou/openapi/query"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func loadYAML(filename string) *openapi3.T {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(filepath.Join("testdata", filename))
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func selectPaths(t *testing.T, doc *openapi3.T, expr string, opts ...openapi.WalkerOption) []string {
+	matches, err := query.Select(context.Background(), doc, expr, opts...)
+	if err != nil {
+		t.Fatalf("%v: %v", expr, err)
+	}
+	paths := []string{}
+	for _, m := range matches {
+		paths = append(paths, strings.Join(m.Path, ":"))
+	}
+	return paths
+}
+
+func TestSelect(t *testing.T) {
+	doc := loadYAML("query.yaml")
+	for _, tc := range []struct {
+		expr  string
+		paths []string
+	}{
+		{`$.info`, []string{"info"}},
+		{`$.info.title`, []string{"info:title"}},
+		{`$.info.extensions['x-audience']`, []string{"info:extensions:x-audience"}},
+		{`$.components.schemas.*`, []string{
+			"components:schemas:Owner",
+			"components:schemas:Pet",
+		}},
+		// String properties with format: uri.
+		{`$..properties[?@.type == 'string' && @.format == 'uri']`, []string{
+			"components:schemas:Owner:properties:website",
+			"components:schemas:Pet:properties:homepage",
+		}},
+		{`$..[?@.format == "uri"]`, []string{
+			"components:schemas:Owner:properties:website",
+			"components:schemas:Pet:properties:homepage",
+			"components:schemas:Pet:properties:links:items",
+		}},
+		// Operations tagged beta.
+		{`$.paths.*[?@.tags[?@ == 'beta']]`, []string{
+			"paths:/owners:get",
+			"paths:/pets:get",
+		}},
+		{`$.paths.*.*.operationId`, []string{
+			"paths:/owners:get:operationId",
+			"paths:/pets:get:operationId",
+			"paths:/pets:post:operationId",
+		}},
+		{`$.paths['/pets'].get.parameters[0]`, []string{"paths:/pets:get:parameters:limit"}},
+		{`$.paths['/pets'].get.parameters[-1]`, []string{"paths:/pets:get:parameters:offset"}},
+		{`$.paths['/pets'].get.parameters.offset.in`, []string{"paths:/pets:get:parameters:offset:in"}},
+		{`$.paths['/pets'].get.parameters[?@.schema.maximum >= 100].name`, []string{"paths:/pets:get:parameters:limit:name"}},
+		{`$.paths['/pets'].get.parameters[?!@.schema.maximum]`, []string{"paths:/pets:get:parameters:offset"}},
+		{`$.components.schemas.Pet.required[1:]`, []string{
+			"components:schemas:Pet:required:1",
+			"components:schemas:Pet:required:2",
+		}},
+		{`$.components.schemas.Pet.required[::-2]`, []string{
+			"components:schemas:Pet:required:2",
+			"components:schemas:Pet:required:0",
+		}},
+		{`$.components.schemas.Pet.required[0, 2]`, []string{
+			"components:schemas:Pet:required:0",
+			"components:schemas:Pet:required:2",
+		}},
+		{`$.components.schemas[?length(@.properties) > 3]`, []string{"components:schemas:Pet"}},
+		{`$.components.schemas[?count(@.properties.*) == 2]`, []string{"components:schemas:Owner"}},
+		{`$.components.schemas[?@.additionalProperties == false]`, []string{"components:schemas:Owner"}},
+		{`$.components.schemas.Pet.properties[?match(@.format, 'ur.')]`, []string{
+			"components:schemas:Pet:properties:homepage",
+		}},
+		{`$.components.schemas.Pet.properties[?search(@.format, 'at')]`, []string{
+			"components:schemas:Pet:properties:born",
+		}},
+		{`$.components.schemas.Pet.properties[?value(@..format) == 'date']`, []string{
+			"components:schemas:Pet:properties:born",
+		}},
+		{`$.components.schemas.Pet.properties[?@.minLength == 1 || (@.format && !(@.format != 'date'))]`, []string{
+			"components:schemas:Pet:properties:born",
+			"components:schemas:Pet:properties:name",
+		}},
+		{`$..['$ref']`, []string{
+			"components:schemas:Pet:properties:owner:$ref",
+			"paths:/pets:get:responses:200:content:application/json:schema:items:$ref",
+			"paths:/pets:post:requestBody:content:application/json:schema:$ref",
+		}},
+		{`$..example[0].links[0]`, []string{
+			"paths:/pets:get:responses:200:content:application/json:example:0:links:0",
+		}},
+		{`$.paths[?$.info.title == 'query'].get.operationId`, []string{
+			"paths:/owners:get:operationId",
+			"paths:/pets:get:operationId",
+		}},
+		{`$.nothing`, []string{}},
+		{`$.info[0]`, []string{}},
+	} {
+		if got, want := selectPaths(t, doc, tc.expr), tc.paths; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.expr, got, want)
+		}
+	}
+}
+
+func TestSelectJSONPointers(t *testing.T) {
+	doc := loadYAML("query.yaml")
+	for _, tc := range []struct {
+		expr  string
+		paths []string
+	}{
+		{`$.info['x-audience']`, []string{"info:x-audience"}},
+		{`$.paths['/pets'].get.parameters[0]`, []string{"paths:/pets:get:parameters:0"}},
+		{`$.paths['/pets'].get.parameters[?@.name == 'offset'].in`, []string{"paths:/pets:get:parameters:1:in"}},
+	} {
+		if got, want := selectPaths(t, doc, tc.expr, openapi.WalkerJSONPointers(true)), tc.paths; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.expr, got, want)
+		}
+	}
+}
+
+func TestSelectFollowRefs(t *testing.T) {
+	doc := loadYAML("query.yaml")
+	expr := `$.paths['/pets'].post..[?@.format == 'uri']`
+	if got, want := len(selectPaths(t, doc, expr)), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got := selectPaths(t, doc, expr, openapi.WalkerFollowRefs(true))
+	want := []string{
+		"paths:/pets:post:requestBody:content:application/json:schema:properties:homepage",
+		"paths:/pets:post:requestBody:content:application/json:schema:properties:links:items",
+		"paths:/pets:post:requestBody:content:application/json:schema:properties:owner:properties:website",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	ctx := context.Background()
+	doc := loadYAML("query.yaml")
+	matches, err := query.MustParse(`$..[?@.format == 'date'].format`).Select(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(matches), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	born := doc.Components.Schemas["Pet"].Value.Properties["born"]
+	if got, want := matches[0].Parent, any(born); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	*matches[0].Node.(*string) = "date-time"
+	if got, want := born.Value.Format, "date-time"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	matches, err = query.Select(ctx, doc, `$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := matches[0].Node, any(doc); len(matches) != 1 || got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		expr, err string
+	}{
+		{``, `expected "$"`},
+		{`$.`, `expected a member name or '*'`},
+		{`$[`, `invalid selector`},
+		{`$['a'`, `expected ',' or ']'`},
+		{`$['a]`, `unterminated string`},
+		{`$['\q']`, `invalid escape \q`},
+		{`$[01]`, `invalid integer "01"`},
+		{`$[9007199254740992]`, `out of range`},
+		{`$[?@.a == @..b]`, `only singular queries can be compared`},
+		{`$[?@.a == 'x'`, `expected ',' or ']'`},
+		{`$[?'x']`, `a literal cannot be used as a test`},
+		{`$[?length(@.a)]`, `the result of length() must be compared`},
+		{`$[?match(@.a, 'x') == true]`, `the result of match() cannot be compared`},
+		{`$[?count('a') == 1]`, `the argument to count() must be a query`},
+		{`$[?length(@.a, @.b) == 1]`, `length() requires 1 arguments`},
+		{`$[?foo(@.a)]`, `unknown function "foo"`},
+		{`$[?match(@.a, '(')]`, `missing closing )`},
+		{`$.a b`, `unexpected " b"`},
+	} {
+		_, err := query.Parse(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: unexpected or missing error: %v", tc.expr, err)
+		}
+	}
+	for _, expr := range []string{
+		`$`,
+		`$.a.b_c.d1`,
+		`$..*`,
+		`$[ 'a' , "b" , * , 1 , -1 , 1:2 , ::-1 , ? @.a ]`,
+		`$["é😀\"\\\/\b\f\n\r\t"]`,
+		`$[?@.a==-1.5e3 && @.b!=null || !(@.c<=true) && @.d>=false]`,
+		`$[?@.a && !@.b]`,
+		`$.ñame`,
+	} {
+		if _, err := query.Parse(expr); err != nil {
+			t.Errorf("%q: %v", expr, err)
+		}
+	}
+}
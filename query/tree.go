@@ -0,0 +1,209 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// node represents a node in the tree that queries are evaluated against.
+type node struct {
+	path     []string
+	value    any
+	parent   *node
+	array    bool
+	children []*node
+	byName   map[string]*node
+	// normalized caches the value of the node converted to its
+	// JSON equivalent.
+	normalized any
+	hasNorm    bool
+}
+
+func (n *node) add(name string, value any) *node {
+	c := &node{
+		path:   append(append(make([]string, 0, len(n.path)+1), n.path...), name),
+		value:  value,
+		parent: n,
+		array:  isArray(value),
+	}
+	n.children = append(n.children, c)
+	if n.byName == nil {
+		n.byName = map[string]*node{}
+	}
+	if _, ok := n.byName[name]; !ok {
+		n.byName[name] = c
+	}
+	return c
+}
+
+func isArray(v any) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	return rv.Kind() == reflect.Slice
+}
+
+// buildTree walks doc to create the tree that queries are evaluated
+// against. The tree contains the nodes visited by the walker with
+// WalkerAllFields, the scalar valued fields of any structs that it
+// visits and the contents of any maps and slices, eg. examples, that
+// are stored as interface values.
+func buildTree(ctx context.Context, doc *openapi3.T, opts ...openapi.WalkerOption) (*node, error) {
+	root := &node{value: doc}
+	addFields(root)
+	stack := []*node{root}
+	visitor := func(ctx context.Context, path []string, parent, value any) (bool, error) {
+		depth := len(openapi.Ancestors(ctx))
+		stack = stack[:depth]
+		p := stack[depth-1]
+		if len(path) == len(p.path) {
+			// The value of an additionalProperties node, ie. its schema,
+			// has the same path and replaces it.
+			p.value, p.array = value, isArray(value)
+			stack = append(stack, p)
+			return true, nil
+		}
+		n := p.add(path[len(path)-1], value)
+		addFields(n)
+		stack = append(stack, n)
+		return true, nil
+	}
+	opts = append([]openapi.WalkerOption{openapi.WalkerOrder(openapi.SortedOrder)}, opts...)
+	opts = append(opts, openapi.WalkerAllFields(true), openapi.WalkerParallel(1))
+	if err := openapi.NewContextWalker(visitor, opts...).WalkContext(ctx, doc); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// addFields adds the scalar fields of structs and the contents of
+// generic maps and slices as children of n.
+func addFields(n *node) {
+	switch v := n.value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			addFields(n.add(k, v[k]))
+		}
+		return
+	case []any:
+		for i, e := range v {
+			addFields(n.add(strconv.Itoa(i), e))
+		}
+		return
+	case *openapi3.AdditionalProperties:
+		return
+	}
+	rv := reflect.ValueOf(n.value)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	rv = rv.Elem()
+	if ref, value := rv.FieldByName("Ref"), rv.FieldByName("Value"); ref.IsValid() && value.IsValid() && value.Kind() == reflect.Pointer {
+		if ref.Len() > 0 {
+			n.add("$ref", ref.Addr().Interface())
+		}
+		if value.IsNil() || value.Elem().Kind() != reflect.Struct {
+			return
+		}
+		rv = value.Elem()
+	}
+	addScalarFields(n, rv)
+}
+
+func addScalarFields(n *node, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			addScalarFields(n, fv)
+			continue
+		}
+		if !openapi.IsScalar(f.Type) || fv.IsZero() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name[:1]) + f.Name[1:]
+		}
+		if fv.Kind() == reflect.Pointer {
+			n.add(name, fv.Interface())
+			continue
+		}
+		n.add(name, fv.Addr().Interface())
+	}
+}
+
+// scalar returns the value of n if it is a JSON scalar, ie. a string,
+// number, boolean or null, with numbers represented as float64.
+func (n *node) scalar() (any, bool) {
+	if ap, ok := n.value.(*openapi3.AdditionalProperties); ok {
+		if ap.Has != nil && ap.Schema == nil {
+			return *ap.Has, true
+		}
+		return nil, false
+	}
+	if n.value == nil {
+		return nil, true
+	}
+	rv := reflect.ValueOf(n.value)
+	if rv.Kind() == reflect.Pointer && openapi.IsScalar(rv.Type()) {
+		if rv.IsNil() {
+			return nil, true
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), true
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return nil, false
+}
+
+// json returns the value of n converted to its JSON equivalent, ie.
+// one of nil, bool, float64, string, []any or map[string]any.
+func (n *node) json() any {
+	if n.hasNorm {
+		return n.normalized
+	}
+	n.hasNorm = true
+	if v, ok := n.scalar(); ok {
+		n.normalized = v
+		return v
+	}
+	buf, err := json.Marshal(n.value)
+	if err == nil {
+		json.Unmarshal(buf, &n.normalized)
+	}
+	return n.normalized
+}
@@ -0,0 +1,329 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// WalkerAllFields controls whether the walker uses reflection to visit
+// every exported field of the kin-openapi types rather than the subset
+// of them that the walker visits by default. With this option:
+//
+//   - every non-empty struct, pointer, map, slice and interface valued
+//     field is visited, but fields with scalar values, such as strings,
+//     are not; their values are available from the node that contains
+//     them.
+//   - every element of a map or slice is visited, including scalars.
+//   - paths are formed from the json names of fields and follow the
+//     same conventions as the default walker, that is, parameters are
+//     identified by name, extensions appear under "extensions", unless
+//     WalkerJSONPointers is specified, and the
+//     values of $refs, such as *openapi3.SchemaRef, appear at the same
+//     path as the $ref itself.
+//   - WalkerFollowRefs applies to all $refs, not just schemas, so that
+//     non-schema $refs are not followed unless it is set.
+//   - the values of interfaces, eg. examples, are visited, but not
+//     their contents.
+//
+// The nodes passed to visitors are pointers for structs and, when they
+// are fields, maps and slices; for example an operation's parameters
+// are passed as *openapi3.Parameters.
+func WalkerAllFields(v bool) WalkerOption {
+	return func(o *walkerOptions) {
+		o.allFields = v
+	}
+}
+
+// IsScalar returns true for the types, or pointers to them, whose
+// values are not visited by the WalkerAllFields walker when they are
+// struct fields, ie. booleans, strings and numbers.
+func IsScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isRef returns true for the $ref types, eg. openapi3.SchemaRef, which
+// consist of a Ref and a pointer to the Value that it refers to.
+func isRef(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	r, rok := t.FieldByName("Ref")
+	v, vok := t.FieldByName("Value")
+	return rok && vok && r.Type.Kind() == reflect.String && v.Type.Kind() == reflect.Pointer &&
+		t.NumField() <= 3
+}
+
+// fieldName returns the path element used for a struct field.
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(f.Name[:1]) + f.Name[1:]
+	}
+	return name
+}
+
+var additionalPropertiesType = reflect.TypeOf(openapi3.AdditionalProperties{})
+
+// reflectValue visits v and its children.
+func (wn nodeWalker) reflectValue(path []string, v reflect.Value) (ok bool, err error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return true, nil
+		}
+		e := v.Elem()
+		switch {
+		case (e.Kind() == reflect.Map || e.Kind() == reflect.Slice) && e.Len() == 0:
+			return true, nil
+		case IsScalar(e.Type()):
+			return wn.visitLeaf(path, v.Interface())
+		case isRef(e.Type()):
+			return wn.reflectRef(path, v)
+		case e.Type() == additionalPropertiesType:
+			// The schema of additionalProperties has the same path.
+			return wn.node(path, v.Interface(), func() (bool, error) {
+				return wn.reflectValue(path, e.FieldByName("Schema"))
+			})
+		}
+		return wn.node(path, v.Interface(), func() (bool, error) {
+			return wn.reflectChildren(path, e)
+		})
+	case reflect.Map, reflect.Slice:
+		if v.Len() == 0 {
+			return true, nil
+		}
+		return wn.node(path, v.Interface(), func() (bool, error) {
+			return wn.reflectChildren(path, v)
+		})
+	case reflect.Interface:
+		if v.IsNil() {
+			return true, nil
+		}
+		return wn.visitLeaf(path, v.Elem().Interface())
+	case reflect.Struct:
+		if v.CanAddr() {
+			return wn.reflectValue(path, v.Addr())
+		}
+		return wn.node(path, v.Interface(), func() (bool, error) {
+			return wn.reflectChildren(path, v)
+		})
+	}
+	return wn.visitLeaf(path, v.Interface())
+}
+
+// reflectRef visits a $ref, ie. a pointer to a type for which isRef
+// is true, and the value that it refers to.
+func (wn nodeWalker) reflectRef(path []string, v reflect.Value) (ok bool, err error) {
+	ref, value := v.Elem().FieldByName("Ref"), v.Elem().FieldByName("Value")
+	if (!wn.opts.followRefs && ref.Len() > 0) || value.IsNil() {
+		return wn.visitLeaf(path, v.Interface())
+	}
+	key := value.Interface()
+	visiting := wn.state.values[key]
+	if s, ok := key.(*openapi3.Schema); ok {
+		visiting = wn.state.schemas[s]
+		if visiting {
+			parent := wn.state.parent()
+			return wn.node(path, v.Interface(), func() (ok bool, err error) {
+				_, ok, err = wn.call(wn.cycleVisitor, path, parent, v.Interface())
+				return
+			})
+		}
+		wn.state.schemas[s] = true
+		defer delete(wn.state.schemas, s)
+	}
+	if visiting {
+		return wn.visitLeaf(path, v.Interface())
+	}
+	wn.state.values[key] = true
+	defer delete(wn.state.values, key)
+	return wn.node(path, v.Interface(), func() (bool, error) {
+		return wn.reflectChildren(path, value.Elem())
+	})
+}
+
+// reflectChildren visits the children of v, which is a struct, map
+// or slice.
+func (wn nodeWalker) reflectChildren(path []string, v reflect.Value) (ok bool, err error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		return wn.reflectFields(path, v)
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range v.MapKeys() {
+			keys[k.String()] = k
+		}
+		return wn.forEach(path, orderedKeys(&wn.opts, path, keys), func(wn nodeWalker, path []string, k string) (bool, error) {
+			return wn.reflectElem(path, v.MapIndex(keys[k]))
+		})
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if ok, err = wn.reflectElem(append(path, wn.elemName(v.Index(i), i)), v.Index(i)); !ok || err != nil {
+				return
+			}
+		}
+	}
+	return true, nil
+}
+
+// elemName returns the path element for the i'th element of a slice.
+// Parameters are identified as per paramName.
+func (wn nodeWalker) elemName(v reflect.Value, i int) string {
+	switch p := v.Interface().(type) {
+	case *openapi3.ParameterRef:
+		if p != nil && p.Value != nil {
+			return wn.paramName(i, p.Value.Name)
+		}
+	case *openapi2.Parameter:
+		if p != nil {
+			return wn.paramName(i, p.Name)
+		}
+	}
+	return strconv.Itoa(i)
+}
+
+// reflectElem visits an element of a map or slice.
+func (wn nodeWalker) reflectElem(path []string, v reflect.Value) (ok bool, err error) {
+	if IsScalar(v.Type()) && v.Kind() != reflect.Pointer {
+		return wn.visitLeaf(path, v.Interface())
+	}
+	return wn.reflectValue(path, v)
+}
+
+// reflectFields visits the fields of v, which is a struct, including
+// those of any embedded structs, followed by any OpenAPI 3.1 keywords
+// and then its extensions.
+func (wn nodeWalker) reflectFields(path []string, v reflect.Value) (ok bool, err error) {
+	var exts reflect.Value
+	if ok, err = wn.reflectStructFields(path, v, &exts); !ok || err != nil {
+		return
+	}
+	if !v.CanAddr() {
+		return true, nil
+	}
+	keywords, err := keywords31(wn.state.decoded, v.Addr().Interface())
+	if err != nil {
+		return false, wn.pathError(path, nil, err)
+	}
+	for _, kw := range keywords {
+		if ok, err = wn.reflectValue(append(path, kw.name), reflect.ValueOf(kw.value)); !ok || err != nil {
+			return
+		}
+	}
+	if !exts.IsValid() || exts.Len() == 0 || !exts.CanAddr() {
+		return true, nil
+	}
+	m := exts.Addr().Interface().(*map[string]interface{})
+	if _, ok := v.Addr().Interface().(*openapi3.T); ok {
+		return wn.extensionsExcept(path, m, doc31Keywords)
+	}
+	return wn.extensions(path, m)
+}
+
+func (wn nodeWalker) reflectStructFields(path []string, v reflect.Value, exts *reflect.Value) (ok bool, err error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		switch {
+		case !f.IsExported():
+			continue
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			if ok, err = wn.reflectStructFields(path, fv, exts); !ok || err != nil {
+				return
+			}
+			continue
+		case f.Name == "Extensions" && f.Type.Kind() == reflect.Map:
+			*exts = fv
+			continue
+		case IsScalar(f.Type) || fv.IsZero():
+			continue
+		}
+		name := fieldName(f)
+		if len(name) == 0 {
+			continue
+		}
+		switch fv.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice:
+			fv = fv.Addr()
+		}
+		if ok, err = wn.reflectValue(append(path, name), fv); !ok || err != nil {
+			return
+		}
+	}
+	return true, nil
+}
+
+type keyword31 struct {
+	name  string
+	value any
+}
+
+// keywords31 returns the OpenAPI 3.1 constructs stored in the Extensions
+// of node, decoded into their typed equivalents, see walk31.go.
+func keywords31(d *decoded31, node any) ([]keyword31, error) {
+	if d == nil {
+		return nil, nil
+	}
+	var exts map[string]any
+	switch n := node.(type) {
+	case *openapi3.T:
+		hooks, err := extensionAs[map[string]*openapi3.PathItem](d, n, n.Extensions, "webhooks")
+		if err != nil || hooks == nil {
+			return nil, err
+		}
+		return []keyword31{{"webhooks", hooks}}, nil
+	case *openapi3.Schema:
+		exts = n.Extensions
+	default:
+		return nil, nil
+	}
+	var kws []keyword31
+	add := func(name string, v any, err error) error {
+		if err == nil && !reflect.ValueOf(v).IsNil() {
+			kws = append(kws, keyword31{name, v})
+		}
+		return err
+	}
+	for _, kw := range []string{"$defs", "dependentSchemas"} {
+		v, err := extensionAs[openapi3.Schemas](d, node, exts, kw)
+		if err := add(kw, v, err); err != nil {
+			return nil, err
+		}
+	}
+	v, err := extensionAs[openapi3.SchemaRefs](d, node, exts, "prefixItems")
+	if err := add("prefixItems", v, err); err != nil {
+		return nil, err
+	}
+	for _, kw := range []string{"if", "then", "else"} {
+		v, err := extensionAs[openapi3.SchemaRef](d, node, exts, kw)
+		if err := add(kw, v, err); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := exts["const"]; ok {
+		kws = append(kws, keyword31{"const", v})
+	}
+	return kws, nil
+}
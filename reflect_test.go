@@ -0,0 +1,194 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// filler populates every exported field of a kin-openapi type, recording
+// the identity of every non-scalar field so that it can be compared with
+// the nodes visited by the walker.
+type filler struct {
+	depth  map[reflect.Type]int
+	fields map[string][]any
+}
+
+func isScalarType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+func isRefType(t reflect.Type) bool {
+	_, rok := t.FieldByName("Ref")
+	_, vok := t.FieldByName("Value")
+	return rok && vok
+}
+
+func (f *filler) fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		et := v.Type().Elem()
+		if f.depth[et] >= 2 {
+			return
+		}
+		v.Set(reflect.New(et))
+		f.depth[et]++
+		f.fill(v.Elem())
+		f.depth[et]--
+	case reflect.Struct:
+		f.fillStruct(v)
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		ev := reflect.New(v.Type().Elem()).Elem()
+		f.fill(ev)
+		v.SetMapIndex(reflect.ValueOf("x-key").Convert(v.Type().Key()), ev)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		f.fill(v.Index(0))
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(new(int)))
+		}
+	}
+}
+
+func (f *filler) fillStruct(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f.fill(fv)
+		if sf.Anonymous || isScalarType(sf.Type) || (sf.Name == "Value" && isRefType(t)) {
+			continue
+		}
+		var id any
+		switch fv.Kind() {
+		case reflect.Pointer:
+			if fv.IsNil() {
+				continue
+			}
+			id = fv.Interface()
+		case reflect.Interface:
+			if fv.IsNil() {
+				continue
+			}
+			id = fv.Elem().Interface()
+		default:
+			id = fv.Addr().Interface()
+		}
+		label := t.Name() + "." + sf.Name
+		f.fields[label] = append(f.fields[label], id)
+	}
+}
+
+func TestWalkAllFieldsCoverage(t *testing.T) {
+	doc := &openapi3.T{}
+	f := &filler{depth: map[reflect.Type]int{}, fields: map[string][]any{}}
+	f.fillStruct(reflect.ValueOf(doc).Elem())
+
+	visited := map[any]bool{}
+	err := openapi.NewWalker(func(path []string, parent, node any) (bool, error) {
+		if reflect.TypeOf(node).Comparable() {
+			visited[node] = true
+		}
+		return true, nil
+	}, openapi.WalkerAllFields(true)).Walk(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := []string{}
+	for label, ids := range f.fields {
+		for _, id := range ids {
+			if !visited[id] {
+				missing = append(missing, label)
+				break
+			}
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("fields not visited: %v", strings.Join(missing, ", "))
+	}
+	if got := len(f.fields); got < 100 {
+		t.Errorf("too few fields: %v", got)
+	}
+}
+
+func allFieldPaths(t *testing.T, walk func(openapi.Visitor, ...openapi.WalkerOption) error) map[string]string {
+	paths := map[string]string{}
+	err := walk(func(path []string, parent, node any) (bool, error) {
+		paths[strings.Join(path, ":")] = fmt.Sprintf("%T", node)
+		return true, nil
+	}, openapi.WalkerAllFields(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func TestWalkAllFields(t *testing.T) {
+	paths := allFieldPaths(t, func(v openapi.Visitor, opts ...openapi.WalkerOption) error {
+		return openapi.NewWalker(v, opts...).Walk(loadYAML("kitchensink.yaml"))
+	})
+	for _, tc := range []struct {
+		path, typ string
+	}{
+		{"components:examples:Cat:value", "map[string]interface {}"},
+		{"components:parameters:Limit:examples:small:value", "float64"},
+		{"components:requestBodies:Pet:content:application/json:schema", "*openapi3.SchemaRef"},
+		{"components:schemas:Pet:required", "*[]string"},
+		{"components:schemas:Pet:required:0", "string"},
+		{"components:schemas:Pet:properties:attributes:additionalProperties", "*openapi3.SchemaRef"},
+		{"components:securitySchemes:oauth:flows:implicit:scopes:read", "string"},
+		{"paths:/pets/{id}:get:parameters", "*openapi3.Parameters"},
+		{"paths:/pets/{id}:get:parameters:verbose", "*openapi3.ParameterRef"},
+		{"paths:/pets/{id}:get:responses:200:links:owner:parameters:ownerId", "string"},
+		{"paths:/pets/{id}:get:responses:200:links:owner:server:variables:port", "*openapi3.ServerVariable"},
+		{"paths:/pets/{id}:put:callbacks:onUpdate:{$request.body#/callbackUrl}:post", "*openapi3.Operation"},
+		{"info:extensions:x-info-extension", "string"},
+	} {
+		if got, want := paths[tc.path], tc.typ; got != want {
+			t.Errorf("%v: got %q, want %q", tc.path, got, want)
+		}
+	}
+
+	paths = allFieldPaths(t, func(v openapi.Visitor, opts ...openapi.WalkerOption) error {
+		return openapi.NewWalker(v, opts...).Walk(loadYAML("openapi31.yaml"))
+	})
+	for _, p := range []string{"webhooks", "extensions:webhooks"} {
+		_, ok := paths[p]
+		if got, want := ok, p == "webhooks"; got != want {
+			t.Errorf("%v: got %v, want %v", p, got, want)
+		}
+	}
+
+	paths = allFieldPaths(t, func(v openapi.Visitor, opts ...openapi.WalkerOption) error {
+		return openapi.NewV2Walker(v, opts...).Walk(loadV2("v2kitchensink.json"))
+	})
+	if got, want := paths["info"], "*openapi3.Info"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	allFieldPaths(t, func(v openapi.Visitor, opts ...openapi.WalkerOption) error {
+		return openapi.NewWalker(v, opts...).Walk(loadYAML("benchling.yaml"))
+	})
+}
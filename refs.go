@@ -0,0 +1,80 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Ref represents a $ref that was followed by the walker.
+type Ref struct {
+	// Ref is the value of the $ref, eg. #/components/schemas/Pet.
+	Ref string
+	// Path is the walker path of the node that contains the $ref.
+	Path []string
+}
+
+// RefChain returns the $refs that were followed to reach the node
+// currently being visited, outermost first. The $ref, if any, of the
+// node itself is not included since its children, rather than the
+// node, are reached through it. An empty chain means that the node is
+// defined inline. It must be called with the context passed to a
+// ContextVisitor.
+func RefChain(ctx context.Context) []Ref {
+	st := walkStateFromContext(ctx)
+	if st == nil {
+		return nil
+	}
+	return append([]Ref{}, st.refs...)
+}
+
+// CanonicalPath returns the path at which the node at path, reached via
+// the supplied chain of $refs, is defined. That is, the path of the
+// target of the innermost local $ref followed by the remainder of path.
+// The path is returned unchanged if the chain is empty or the innermost
+// $ref refers to another document.
+func CanonicalPath(chain []Ref, path []string) []string {
+	if len(chain) == 0 {
+		return path
+	}
+	last := chain[len(chain)-1]
+	if !strings.HasPrefix(last.Ref, "#") || len(last.Path) > len(path) {
+		return path
+	}
+	target, err := ParseJSONPointer(last.Ref)
+	if err != nil {
+		return path
+	}
+	return append(target, path[len(last.Path):]...)
+}
+
+// RefOf returns the $ref, if any, of node, which may be any of the
+// openapi3 $ref types, eg. *openapi3.SchemaRef.
+func RefOf(node any) string {
+	switch n := node.(type) {
+	case *openapi3.SchemaRef:
+		return n.Ref
+	case *openapi3.ParameterRef:
+		return n.Ref
+	case *openapi3.HeaderRef:
+		return n.Ref
+	case *openapi3.RequestBodyRef:
+		return n.Ref
+	case *openapi3.ResponseRef:
+		return n.Ref
+	case *openapi3.SecuritySchemeRef:
+		return n.Ref
+	case *openapi3.ExampleRef:
+		return n.Ref
+	case *openapi3.LinkRef:
+		return n.Ref
+	case *openapi3.CallbackRef:
+		return n.Ref
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+)
+
+func TestRefChain(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	chains := map[string][]string{}
+	canonical := map[string]string{}
+	wk := openapi.NewContextWalker(func(ctx context.Context, path []string, parent, node any) (bool, error) {
+		p := strings.Join(path, ":")
+		chain := openapi.RefChain(ctx)
+		for _, r := range chain {
+			chains[p] = append(chains[p], r.Ref+"@"+strings.Join(r.Path, ":"))
+		}
+		canonical[p] = strings.Join(openapi.CanonicalPath(chain, path), ":")
+		return true, nil
+	}, openapi.WalkerFollowRefs(true))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path, canonical string
+		chain           []string
+	}{
+		{"components:schemas:Pet:properties:name", "components:schemas:Pet:properties:name", nil},
+		// The node containing the $ref is not itself reached via the $ref.
+		{"components:schemas:Shape:oneOf:0", "components:schemas:Shape:oneOf:0", nil},
+		{"components:schemas:Shape:oneOf:0:allOf:1",
+			"components:schemas:Cat:allOf:1",
+			[]string{"#/components/schemas/Cat@components:schemas:Shape:oneOf:0"}},
+		{"components:schemas:Shape:oneOf:0:allOf:0:properties:name",
+			"components:schemas:Pet:properties:name",
+			[]string{
+				"#/components/schemas/Cat@components:schemas:Shape:oneOf:0",
+				"#/components/schemas/Pet@components:schemas:Shape:oneOf:0:allOf:0",
+			}},
+		{"paths:/pets/{id}:get:parameters:limit:schema",
+			"components:parameters:Limit:schema",
+			[]string{"#/components/parameters/Limit@paths:/pets/{id}:get:parameters:limit"}},
+		{"paths:/pets/{id}:get:responses:default:content:application/json",
+			"components:responses:Error:content:application/json",
+			[]string{"#/components/responses/Error@paths:/pets/{id}:get:responses:default"}},
+	} {
+		if got, want := chains[tc.path], tc.chain; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.path, got, want)
+		}
+		if got, want := canonical[tc.path], tc.canonical; got != want {
+			t.Errorf("%v: got %v, want %v", tc.path, got, want)
+		}
+	}
+
+	chain := []openapi.Ref{{Ref: "other.yaml#/components/schemas/Pet", Path: []string{"a"}}}
+	if got, want := openapi.CanonicalPath(chain, []string{"a", "b"}), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
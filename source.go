@@ -0,0 +1,211 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source represents the original YAML (or JSON) text of an openapi document
+// and is used to recover information that is lost when the document is
+// parsed by the kin-openapi loader, such as the order of keys in maps and
+// the line and column of each node.
+type Source struct {
+	root     *yaml.Node
+	data     []byte
+	filename string
+}
+
+// ReadSource reads and parses the specified file, its name is included
+// in the Positions returned by the Source.
+func ReadSource(filename string) (*Source, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	src, err := NewSource(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	src.filename = filename
+	return src, nil
+}
+
+// NewSource parses the supplied YAML or JSON data.
+func NewSource(data []byte) (*Source, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, fmt.Errorf("not a yaml document")
+	}
+	return &Source{root: doc.Content[0], data: data}, nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	_, v := mappingEntry(n, key)
+	return v
+}
+
+func mappingEntry(n *yaml.Node, key string) (k, v *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i], resolveAlias(n.Content[i+1])
+		}
+	}
+	return nil, nil
+}
+
+func sequenceValue(n *yaml.Node, key string) *yaml.Node {
+	if i, err := strconv.Atoi(key); err == nil {
+		if i >= 0 && i < len(n.Content) {
+			return resolveAlias(n.Content[i])
+		}
+		return nil
+	}
+	// Parameters are identified by name rather than index in walker paths.
+	for _, c := range n.Content {
+		c = resolveAlias(c)
+		if c.Kind != yaml.MappingNode {
+			continue
+		}
+		if v := mappingValue(c, "name"); v != nil && v.Value == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// Lookup returns the yaml.Node, if any, that corresponds to the supplied
+// walker path.
+func (s *Source) Lookup(path []string) *yaml.Node {
+	_, n, _ := s.lookup(path)
+	return n
+}
+
+// lookup returns the node for path, and the key for that node if it is
+// a map entry. If there is no such node it returns the deepest node
+// that does exist along with the number of elements of path that it
+// corresponds to.
+func (s *Source) lookup(path []string) (key, n *yaml.Node, depth int) {
+	if s == nil {
+		return nil, nil, 0
+	}
+	n = s.root
+	for i, p := range path {
+		var k, v *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			k, v = mappingEntry(n, p)
+			if v == nil && p == "extensions" {
+				// Extensions are stored inline in the source.
+				continue
+			}
+		case yaml.SequenceNode:
+			v = sequenceValue(n, p)
+		}
+		if v == nil {
+			return nil, nil, i
+		}
+		key, n = k, v
+	}
+	return key, n, len(path)
+}
+
+// Position represents a position in the source of a document.
+type Position struct {
+	Filename string
+	Line     int // starting at 1
+	Column   int // starting at 1
+}
+
+// IsValid returns true if the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form filename:line:column, or
+// line:column if the filename is unknown, and "-" if the position
+// itself is unknown.
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if len(s) > 0 {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if len(s) == 0 {
+		s = "-"
+	}
+	return s
+}
+
+// Position returns the position in the source of the node at the
+// specified walker path. For map entries this is the position of the
+// key. If there is no such node, for example because it was added by
+// a transform, the position of its nearest ancestor that does exist
+// is returned.
+func (s *Source) Position(path []string) Position {
+	if s == nil {
+		return Position{}
+	}
+	key, n, depth := s.lookup(path)
+	if depth < len(path) {
+		key, n, _ = s.lookup(path[:depth])
+	}
+	if key != nil {
+		n = key
+	}
+	return Position{Filename: s.filename, Line: n.Line, Column: n.Column}
+}
+
+// Keys returns the keys of the map at the specified walker path in
+// the order that they appear in the source.
+func (s *Source) Keys(path []string) []string {
+	n := s.Lookup(path)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(n.Content)/2)
+	for i := 0; i < len(n.Content); i += 2 {
+		keys = append(keys, n.Content[i].Value)
+	}
+	return keys
+}
+
+// sortKeys sorts keys into the order in which they appear in the source
+// for the map at path. Keys that do not appear in the source are
+// sorted lexicographically and placed after those that do.
+func (s *Source) sortKeys(path []string, keys []string) {
+	order := map[string]int{}
+	for i, k := range s.Keys(path) {
+		order[k] = i
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		oi, iok := order[keys[i]]
+		oj, jok := order[keys[j]]
+		switch {
+		case iok && jok:
+			return oi < oj
+		case iok != jok:
+			return iok
+		}
+		return keys[i] < keys[j]
+	})
+}
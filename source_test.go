@@ -0,0 +1,57 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+)
+
+func TestSourcePositions(t *testing.T) {
+	filename := filepath.Join("testdata", "kitchensink.yaml")
+	src, err := openapi.ReadSource(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		path []string
+		pos  string
+	}{
+		{nil, "1:1"},
+		{[]string{"info"}, "2:1"},
+		{[]string{"info", "extensions", "x-info-extension"}, "5:3"},
+		{[]string{"servers", "0", "variables", "region"}, "9:7"},
+		{[]string{"paths", "/pets/{id}", "get", "parameters", "verbose"}, "40:11"},
+		{[]string{"components", "schemas", "Pet", "properties", "name"}, "124:9"},
+		// Nodes that are not in the source are reported at their
+		// nearest ancestor.
+		{[]string{"components", "schemas", "Pet", "properties", "missing"}, "121:7"},
+	} {
+		if got, want := src.Position(tc.path).String(), filename+":"+tc.pos; got != want {
+			t.Errorf("%v: %v: got %v, want %v", i, tc.path, got, want)
+		}
+	}
+
+	var nilSource *openapi.Source
+	if got, want := nilSource.Position([]string{"info"}).String(), "-"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	doc := loadYAML("kitchensink.yaml")
+	errFail := errors.New("fail")
+	err = openapi.NewWalker(func(path []string, parent, node any) (bool, error) {
+		if len(path) == 5 && path[4] == "tags" {
+			return false, errFail
+		}
+		return true, nil
+	}, openapi.WalkerSource(src)).Walk(doc)
+	if got, want := fmt.Sprint(err), filename+":127:9: components:schemas:Pet:properties:tags: fail"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteJSON writes s to w as indented JSON.
+func (s *Stats) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteTable writes s to w as a human readable table.
+func (s *Stats) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	section := func(title string) {
+		fmt.Fprintf(tw, "%s\n", title)
+	}
+	row := func(name string, value any) {
+		fmt.Fprintf(tw, "  %s\t%v\n", name, value)
+	}
+	counts := func(m map[string]int) {
+		for _, k := range sortedKeys(m) {
+			row(k, m[k])
+		}
+	}
+
+	section("operations")
+	row("total", s.Operations)
+	row("untagged", s.UntaggedOperations)
+	section("operations by method")
+	counts(s.OperationsByMethod)
+	section("operations by tag")
+	counts(s.OperationsByTag)
+
+	section("schemas")
+	row("total", s.Schemas)
+	row("inline", s.InlineSchemas)
+	row("$refs", s.SchemaRefs)
+	row("allOf", s.AllOf)
+	row("oneOf", s.OneOf)
+	row("anyOf", s.AnyOf)
+	row("max depth", s.MaxDepth)
+	if len(s.MaxDepthPath) > 0 {
+		row("max depth path", strings.Join(s.MaxDepthPath, ":"))
+	}
+	section("schemas by type")
+	counts(s.SchemasByType)
+
+	section("$refs")
+	row("total", s.Refs)
+	section("unused components")
+	for _, c := range s.UnusedComponents {
+		fmt.Fprintf(tw, "  %s\n", c)
+	}
+	section("largest schemas")
+	for _, l := range s.LargestSchemas {
+		row(l.Name, fmt.Sprintf("%v schemas, %v properties", l.Size, l.Properties))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package stats provides support for profiling openapi3 documents, for
+// example to determine which transforms a document requires before it
+// is used for code generation. The statistics gathered include counts
+// of operations by method and tag, of schemas by type, of $refs and of
+// the use of allOf, oneOf and anyOf, as well as the components that are
+// never referred to and the largest schemas.
+package stats
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Stats represents the statistics gathered for a document.
+type Stats struct {
+	// Operations is the total number of operations.
+	Operations int `json:"operations"`
+	// OperationsByMethod counts operations by upper case HTTP method.
+	OperationsByMethod map[string]int `json:"operationsByMethod"`
+	// OperationsByTag counts operations by tag, an operation with
+	// multiple tags is counted once for each of them.
+	OperationsByTag map[string]int `json:"operationsByTag"`
+	// UntaggedOperations is the number of operations with no tags.
+	UntaggedOperations int `json:"untaggedOperations"`
+
+	// Schemas is the total number of schemas that are defined, ie.
+	// that are not $refs, whether inline or in components.
+	Schemas int `json:"schemas"`
+	// SchemasByType counts schemas by their type, schemas with no
+	// type are counted as "untyped".
+	SchemasByType map[string]int `json:"schemasByType"`
+	// InlineSchemas is the number of schemas that are not defined in
+	// components.schemas.
+	InlineSchemas int `json:"inlineSchemas"`
+	// SchemaRefs is the number of schemas that are $refs.
+	SchemaRefs int `json:"schemaRefs"`
+	// MaxDepth is the greatest number of schemas that enclose, or are,
+	// any schema, ie. a schema with no enclosing schema has a depth of 1.
+	MaxDepth int `json:"maxDepth"`
+	// MaxDepthPath is the path of a schema at MaxDepth.
+	MaxDepthPath []string `json:"maxDepthPath,omitempty"`
+	// AllOf, OneOf and AnyOf count the schemas that use allOf, oneOf
+	// and anyOf respectively.
+	AllOf int `json:"allOf"`
+	OneOf int `json:"oneOf"`
+	AnyOf int `json:"anyOf"`
+
+	// Refs is the total number of $refs of all kinds.
+	Refs int `json:"refs"`
+	// UnusedComponents lists, in $ref form, the components that
+	// are not referred to by any $ref, discriminator mapping or, for
+	// security schemes, security requirement.
+	UnusedComponents []string `json:"unusedComponents"`
+	// LargestSchemas lists the largest schemas in components.schemas,
+	// largest first.
+	LargestSchemas []SchemaSize `json:"largestSchemas"`
+}
+
+// SchemaSize represents the size of a schema defined in
+// components.schemas.
+type SchemaSize struct {
+	Name string `json:"name"`
+	// Size is the number of schemas, including itself, that the
+	// schema contains.
+	Size int `json:"size"`
+	// Properties is the number of properties that the schema has.
+	Properties int `json:"properties"`
+}
+
+// NumLargestSchemas is the number of schemas reported in
+// Stats.LargestSchemas.
+var NumLargestSchemas = 10
+
+// Compute walks doc to gather its statistics. The options are passed to
+// the walker. Nodes that are $refs are counted as such, but the nodes
+// that they refer to are counted only where they are defined and hence
+// WalkerFollowRefs has no effect.
+func Compute(ctx context.Context, doc *openapi3.T, opts ...openapi.WalkerOption) (*Stats, error) {
+	c := &collector{
+		stats: &Stats{
+			OperationsByMethod: map[string]int{},
+			OperationsByTag:    map[string]int{},
+			SchemasByType:      map[string]int{},
+		},
+		used:  map[string]bool{},
+		sizes: map[string]*SchemaSize{},
+	}
+	// The collector is not safe for concurrent use.
+	opts = append(opts, openapi.WalkerParallel(1))
+	if err := openapi.NewContextWalker(c.visit, opts...).WalkContext(ctx, doc); err != nil {
+		return nil, err
+	}
+	c.security(doc.Security)
+	c.unused(doc.Components)
+	c.largest()
+	return c.stats, nil
+}
+
+type collector struct {
+	stats *Stats
+	used  map[string]bool
+	sizes map[string]*SchemaSize
+}
+
+func (c *collector) visit(ctx context.Context, path []string, parent, node any) (bool, error) {
+	if ref := openapi.RefOf(node); len(ref) > 0 {
+		c.stats.Refs++
+		c.use(ref)
+		if _, ok := node.(*openapi3.SchemaRef); ok {
+			c.stats.SchemaRefs++
+		}
+		return false, openapi.SkipNode
+	}
+	switch n := node.(type) {
+	case *openapi3.Operation:
+		c.operation(path, n)
+	case *openapi3.SchemaRef:
+		c.schema(ctx, path, n)
+	}
+	return true, nil
+}
+
+func (c *collector) operation(path []string, op *openapi3.Operation) {
+	c.stats.Operations++
+	c.stats.OperationsByMethod[strings.ToUpper(path[len(path)-1])]++
+	for _, tag := range op.Tags {
+		c.stats.OperationsByTag[tag]++
+	}
+	if len(op.Tags) == 0 {
+		c.stats.UntaggedOperations++
+	}
+	if op.Security != nil {
+		c.security(*op.Security)
+	}
+}
+
+func (c *collector) schema(ctx context.Context, path []string, sref *openapi3.SchemaRef) {
+	s := sref.Value
+	if s == nil {
+		return
+	}
+	st := c.stats
+	st.Schemas++
+	if len(s.Type) == 0 {
+		st.SchemasByType["untyped"]++
+	} else {
+		st.SchemasByType[s.Type]++
+	}
+	if len(s.AllOf) > 0 {
+		st.AllOf++
+	}
+	if len(s.OneOf) > 0 {
+		st.OneOf++
+	}
+	if len(s.AnyOf) > 0 {
+		st.AnyOf++
+	}
+	if s.Discriminator != nil {
+		for _, ref := range s.Discriminator.Mapping {
+			c.use(ref)
+		}
+	}
+	depth := 1
+	for _, a := range openapi.Ancestors(ctx) {
+		if _, ok := a.Node.(*openapi3.SchemaRef); ok {
+			depth++
+		}
+	}
+	if depth > st.MaxDepth {
+		st.MaxDepth = depth
+		st.MaxDepthPath = append([]string{}, path...)
+	}
+	if len(path) < 3 || path[0] != "components" || path[1] != "schemas" {
+		st.InlineSchemas++
+		return
+	}
+	name := path[2]
+	size := c.sizes[name]
+	if size == nil {
+		size = &SchemaSize{Name: name}
+		c.sizes[name] = size
+	}
+	size.Size++
+	if len(path) == 3 {
+		size.Properties = len(s.Properties)
+		return
+	}
+	st.InlineSchemas++
+}
+
+// use records the component, if any, referred to by ref.
+func (c *collector) use(ref string) {
+	if !strings.HasPrefix(ref, "#/components/") {
+		return
+	}
+	p, err := openapi.ParseJSONPointer(ref)
+	if err != nil || len(p) < 3 {
+		return
+	}
+	c.used[p[1]+"/"+p[2]] = true
+}
+
+func (c *collector) security(reqs openapi3.SecurityRequirements) {
+	for _, req := range reqs {
+		for name := range req {
+			c.used["securitySchemes/"+name] = true
+		}
+	}
+}
+
+func (c *collector) unused(comps *openapi3.Components) {
+	if comps == nil {
+		return
+	}
+	c.stats.UnusedComponents = []string{}
+	add := func(kind string, names []string) {
+		for _, name := range names {
+			if !c.used[kind+"/"+name] {
+				c.stats.UnusedComponents = append(c.stats.UnusedComponents,
+					"#"+openapi.JSONPointer([]string{"components", kind, name}))
+			}
+		}
+	}
+	add("schemas", sortedKeys(comps.Schemas))
+	add("parameters", sortedKeys(comps.Parameters))
+	add("headers", sortedKeys(comps.Headers))
+	add("requestBodies", sortedKeys(comps.RequestBodies))
+	add("responses", sortedKeys(comps.Responses))
+	add("securitySchemes", sortedKeys(comps.SecuritySchemes))
+	add("examples", sortedKeys(comps.Examples))
+	add("links", sortedKeys(comps.Links))
+	add("callbacks", sortedKeys(comps.Callbacks))
+}
+
+func (c *collector) largest() {
+	sizes := make([]SchemaSize, 0, len(c.sizes))
+	for _, s := range c.sizes {
+		sizes = append(sizes, *s)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Size != sizes[j].Size {
+			return sizes[i].Size > sizes[j].Size
+		}
+		return sizes[i].Name < sizes[j].Name
+	})
+	if len(sizes) > NumLargestSchemas {
+		sizes = sizes[:NumLargestSchemas]
+	}
+	c.stats.LargestSchemas = sizes
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	k := make([]string, 0, len(m))
+	for n := range m {
+		k = append(k, n)
+	}
+	sort.Strings(k)
+	return k
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package stats_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi/stats"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func loadYAML(filename string) *openapi3.T {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(filepath.Join("testdata", filename))
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func TestStats(t *testing.T) {
+	doc := loadYAML("stats.yaml")
+	st, err := stats.Compute(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &stats.Stats{
+		Operations:         3,
+		OperationsByMethod: map[string]int{"GET": 2, "POST": 1},
+		OperationsByTag:    map[string]int{"pets": 2, "beta": 1},
+		UntaggedOperations: 1,
+		Schemas:            19,
+		SchemasByType: map[string]int{
+			"array":   2,
+			"integer": 4,
+			"object":  7,
+			"string":  3,
+			"untyped": 3,
+		},
+		InlineSchemas: 12,
+		SchemaRefs:    7,
+		MaxDepth:      3,
+		MaxDepthPath:  []string{"components", "schemas", "Owner", "allOf", "1", "properties", "pets"},
+		AllOf:         1,
+		OneOf:         1,
+		AnyOf:         1,
+		Refs:          9,
+		UnusedComponents: []string{
+			"#/components/schemas/Unused",
+			"#/components/parameters/offset",
+			"#/components/securitySchemes/basic",
+		},
+		LargestSchemas: []stats.SchemaSize{
+			{Name: "Owner", Size: 3, Properties: 0},
+			{Name: "Pet", Size: 3, Properties: 3},
+			{Name: "Unused", Size: 3, Properties: 0},
+			{Name: "Person", Size: 2, Properties: 1},
+			{Name: "Bird", Size: 1, Properties: 0},
+			{Name: "Cat", Size: 1, Properties: 0},
+			{Name: "Dog", Size: 1, Properties: 0},
+		},
+	}
+	if !reflect.DeepEqual(st, want) {
+		got, _ := json.MarshalIndent(st, "", "  ")
+		t.Errorf("got %s", got)
+	}
+
+	out := &strings.Builder{}
+	if err := st.WriteJSON(out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded stats.Stats
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, want) {
+		t.Errorf("got %v, want %v", decoded, want)
+	}
+
+	out.Reset()
+	if err := st.WriteTable(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"  GET   2",
+		"  #/components/schemas/Unused",
+		"  Pet     3 schemas, 3 properties",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+}
+
+func TestStatsRequestBodiesAndCallbacks(t *testing.T) {
+	doc := loadYAML("callbacks.yaml")
+	st, err := stats.Compute(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The operations within the inline callback and the Error callback
+	// component are counted, and the schemas referred to only from them
+	// and from the Subscription request body are used.
+	if got, want := st.Operations, 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := st.OperationsByMethod, map[string]int{"POST": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := st.UnusedComponents, []string{"#/components/schemas/Unused"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := st.Refs, 5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatOptions represents the style used for formatting documents by
+// FormatV3 and FormatV2, via FormatStyle, and by AsYAML. The zero value
+// represents the default style: 2 space indentation, compact JSON,
+// no limit on the width of lines and the quoting and styles chosen by
+// gopkg.in/yaml.v3.
+type FormatOptions struct {
+	// Indent is the number of spaces used for each level of
+	// indentation, 2 if zero.
+	Indent int
+	// PrettyJSON formats JSON over multiple lines, indented as per
+	// Indent, rather than compactly.
+	PrettyJSON bool
+	// LiteralBlocks formats multi-line strings, such as descriptions,
+	// as literal block scalars (|) rather than as quoted strings with
+	// escaped newlines.
+	LiteralBlocks bool
+	// FlowArrays formats arrays of scalars that fit on a single line,
+	// eg. the required properties or enum values of a schema, in flow
+	// style, eg. required: [id, name]. Lines are limited to LineWidth,
+	// or 80 characters if LineWidth is zero.
+	FlowArrays bool
+	// LineWidth, if non-zero, is the maximum width of a line. Longer
+	// strings are folded over multiple lines at the spaces they contain.
+	// Lines may still exceed the width since keys, literal block scalars
+	// and strings without spaces are never folded.
+	LineWidth int
+}
+
+// FormatStyle specifies the style to be used for formatting a document.
+// When used with FormatSource only LiteralBlocks is used, for the
+// entries that are changed or added, since the source's own formatting,
+// including its indentation, is otherwise preserved.
+func FormatStyle(style FormatOptions) FormatOption {
+	return func(o *formatOptions) {
+		o.style = style
+	}
+}
+
+func (fo FormatOptions) indent() int {
+	if fo.Indent <= 0 {
+		return 2
+	}
+	return fo.Indent
+}
+
+// write writes the JSON value v to w as YAML or JSON with the keys of
+// objects in the order specified by ko, see Encoder.
+func (fo FormatOptions) write(w io.Writer, v jsonValue, ko *keyOrder, isYAML bool) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	if isYAML {
+		yw := &yamlWriter{w: bw, style: fo}
+		err = yw.write(v, ko)
+	} else {
+		indent := ""
+		if fo.PrettyJSON {
+			indent = strings.Repeat(" ", fo.indent())
+		}
+		err = writeJSON(bw, v, ko, indent, 0)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// formatYAML returns the YAML for n, changing the style of its nodes as
+// required, when it is to be indented by shift spaces, ie. the width
+// available for FlowArrays and LineWidth is reduced by shift.
+func (fo FormatOptions) formatYAML(n *yaml.Node, shift int) ([]byte, error) {
+	if fo.LiteralBlocks {
+		setLiteralStyle(n)
+	}
+	quoteIndicatedBlocks(n)
+	if fo.FlowArrays {
+		out, err := fo.encode(n)
+		if err != nil {
+			return nil, err
+		}
+		width := fo.LineWidth
+		if width <= 0 {
+			width = 80
+		}
+		if err := fo.setFlowStyle(n, out, width-shift); err != nil {
+			return nil, err
+		}
+	}
+	out, err := fo.encode(n)
+	if err != nil || fo.LineWidth <= 0 {
+		return out, err
+	}
+	return fo.fold(out, fo.LineWidth-shift), nil
+}
+
+func (fo FormatOptions) encode(n *yaml.Node) ([]byte, error) {
+	out := &bytes.Buffer{}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(fo.indent())
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	err := enc.Close()
+	return out.Bytes(), err
+}
+
+func parseYAML(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// setLiteralStyle sets the style of all multi-line strings within n to
+// be literal. gopkg.in/yaml.v3 will quote any that cannot be represented
+// as literal block scalars, eg. those with trailing spaces.
+func setLiteralStyle(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.Contains(n.Value, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	for _, c := range n.Content {
+		setLiteralStyle(c)
+	}
+}
+
+// quoteIndicatedBlocks sets the style of multi-line strings that start
+// with a space or newline to be double quoted. gopkg.in/yaml.v3 formats
+// such strings as block scalars with an indentation indicator that is
+// incorrect for maps within arrays unless the indentation is 2 and
+// that loses a leading newline.
+func quoteIndicatedBlocks(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.Contains(n.Value, "\n") &&
+		(n.Value[0] == ' ' || n.Value[0] == '\n') {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		quoteIndicatedBlocks(c)
+	}
+}
+
+// setFlowStyle sets the style of the arrays of scalars within n that
+// are the values of map entries to be flow style if the entry will fit
+// on a single line of the specified width. The block style YAML for n,
+// out, is used to determine the column at which each entry starts.
+func (fo FormatOptions) setFlowStyle(n *yaml.Node, out []byte, width int) error {
+	parsed, err := parseYAML(out)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(out), "\n")
+	var walk func(n, p *yaml.Node) error
+	walk = func(n, p *yaml.Node) error {
+		for i, c := range n.Content {
+			if n.Kind == yaml.MappingNode && i%2 == 1 && isScalarArray(c) {
+				flow := *c
+				flow.Style = yaml.FlowStyle
+				text, err := fo.encode(&flow)
+				if err != nil {
+					return err
+				}
+				// The key is on a line by itself in block style.
+				key := []rune(lines[p.Content[i-1].Line-1])
+				if len(key)+1+len([]rune(strings.TrimSpace(string(text)))) <= width {
+					c.Style = yaml.FlowStyle
+				}
+				continue
+			}
+			if err := walk(c, p.Content[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(n, parsed)
+}
+
+func isScalarArray(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return false
+	}
+	for _, c := range n.Content {
+		if c.Kind != yaml.ScalarNode || strings.Contains(c.Value, "\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// fold returns out with the strings on lines that are longer than width
+// folded over multiple lines. It returns out unchanged if the folded YAML
+// does not represent the same data.
+func (fo FormatOptions) fold(out []byte, width int) []byte {
+	parsed, err := parseYAML(out)
+	if err != nil {
+		return out
+	}
+	lines := strings.Split(string(out), "\n")
+	folded := false
+	foldScalar := func(n *yaml.Node, indent int) {
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!str" || n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return
+		}
+		line := []rune(lines[n.Line-1])
+		if len(line) <= width {
+			return
+		}
+		start := n.Column - 1
+		text := foldText(line[start:], start, indent, width, n.Style == 0)
+		lines[n.Line-1] = string(line[:start]) + text
+		folded = true
+	}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Style&yaml.FlowStyle != 0 {
+			return
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				foldScalar(n.Content[i], n.Content[i-1].Column-1+fo.indent())
+				walk(n.Content[i])
+			}
+		case yaml.SequenceNode:
+			for _, c := range n.Content {
+				foldScalar(c, c.Column-1)
+				walk(c)
+			}
+		}
+	}
+	walk(parsed)
+	if !folded {
+		return out
+	}
+	result := []byte(strings.Join(lines, "\n"))
+	var a, b any
+	if yaml.Unmarshal(out, &a) != nil || yaml.Unmarshal(result, &b) != nil || !reflect.DeepEqual(a, b) {
+		return out
+	}
+	return result
+}
+
+// foldText folds text, which starts at column col, so that each line is
+// no wider than width where possible. Continuation lines are indented
+// by indent spaces. Lines are broken at single spaces and, for plain
+// scalars, not before characters that may be mistaken for YAML syntax.
+func foldText(text []rune, col, indent, width int, plain bool) string {
+	canBreak := func(i int) bool {
+		if i == 0 || i+1 >= len(text) || text[i] != ' ' || text[i-1] == ' ' || text[i-1] == '\\' || text[i+1] == ' ' {
+			return false
+		}
+		return !plain || !strings.ContainsRune("#-?:,[]{}&*!|>'\"%@`", text[i+1])
+	}
+	out := &strings.Builder{}
+	from, last := 0, -1
+	for i := range text {
+		if col+i-from+1 > width && last >= 0 {
+			out.WriteString(string(text[from:last]))
+			out.WriteString("\n" + strings.Repeat(" ", indent))
+			from, col, last = last+1, indent, -1
+		}
+		if canBreak(i) {
+			last = i
+		}
+	}
+	out.WriteString(string(text[from:]))
+	return out.String()
+}
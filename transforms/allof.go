@@ -5,6 +5,7 @@
 package transforms
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -20,9 +21,11 @@ func init() {
 
 type allOf struct {
 	Path           []string `yaml:",flow"`
+	Query          string   `yaml:"query,omitempty"`
 	IgnoreNonType  bool     `yaml:"ignoreNonType"`
 	PromoteNonType []string `yaml:"promoteNonType,flow"`
 	MergeNonType   []string `yaml:"mergeNonType,flow"`
+	selector       selector
 }
 
 type allOfTransformer struct {
@@ -38,6 +41,13 @@ func (t *allOfTransformer) Configure(node yaml.Node) error {
 	if err := node.Decode(&ao); err != nil {
 		return err
 	}
+	for i, r := range ao {
+		sel, err := newSelector(r.Path, r.Query)
+		if err != nil {
+			return err
+		}
+		ao[i].selector = sel
+	}
 	t.AllOfRules = ao
 	return nil
 }
@@ -56,8 +66,30 @@ allOf entries that are not themselves schemas with type information.`))
 }
 
 func (t *allOfTransformer) Transform(doc *openapi3.T) (*openapi3.T, error) {
-	walker := openapi.NewWalker(t.visitor)
-	return doc, walker.Walk(doc)
+	return t.TransformContext(context.Background(), doc)
+}
+
+func (t *allOfTransformer) TransformContext(ctx context.Context, doc *openapi3.T) (*openapi3.T, error) {
+	if err := t.prepare(ctx, doc); err != nil {
+		return nil, err
+	}
+	// The allOf transform modifies the schemas referred to by the allOf
+	// entries, rather than just the node being visited, and so does not
+	// implement Walker since it cannot share a walk with other transforms.
+	// The schemas that it modifies may also be reached via other nodes
+	// and hence it always walks the document sequentially.
+	opts := append(walkerOptions(ctx), openapi.WalkerParallel(1))
+	walker := openapi.NewTypedWalker(openapi.TypedVisitor{Schema: t.visitor}, opts...)
+	return doc, walk(ctx, walker, doc)
+}
+
+func (t *allOfTransformer) prepare(ctx context.Context, doc *openapi3.T) error {
+	for i := range t.AllOfRules {
+		if err := t.AllOfRules[i].selector.prepare(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func hasSchema(s *openapi3.SchemaRef) bool {
@@ -113,7 +145,6 @@ func (t *allOfTransformer) handleTransformation(r allOf, schema *openapi3.Schema
 			if prev == nil {
 				return fmt.Errorf("allOf entry: %v cannot be merged since there is previous schema with a type to merge it with", i)
 			}
-			fmt.Printf("merging.....\n")
 			n, err := handleMerge(prev.Value, s.Value, r.MergeNonType)
 			if err != nil {
 				return err
@@ -131,11 +162,7 @@ func (t *allOfTransformer) handleTransformation(r allOf, schema *openapi3.Schema
 	return nil
 }
 
-func (t *allOfTransformer) visitor(path []string, parent, node any) (bool, error) {
-	schema, ok := node.(*openapi3.SchemaRef)
-	if !ok {
-		return true, nil
-	}
+func (t *allOfTransformer) visitor(path []string, parent any, schema *openapi3.SchemaRef) (bool, error) {
 	if len(schema.Value.AllOf) == 0 {
 		return true, nil
 	}
@@ -143,11 +170,11 @@ func (t *allOfTransformer) visitor(path []string, parent, node any) (bool, error
 		return true, nil
 	}
 	for _, r := range t.AllOfRules {
-		if !match(path, r.Path) {
+		if !r.selector.match(path) {
 			continue
 		}
 		if err := t.handleTransformation(r, schema); err != nil {
-			return false, fmt.Errorf("%v: %v", strings.Join(path, ":"), err)
+			return false, err
 		}
 	}
 	return true, nil
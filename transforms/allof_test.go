@@ -5,6 +5,7 @@
 package transforms_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/cosnicolaou/openapi/transforms"
@@ -79,3 +80,49 @@ MergeEg:
       type: object
 `)
 }
+
+func TestTransformAllAllOf(t *testing.T) {
+	// The allOf transform modifies the schemas referred to by allOf
+	// entries and so must not share a walk with the discriminator
+	// transform, which would otherwise add pet_type to the required
+	// properties of Pet after, rather than before, they are merged.
+	config := discrimatorfConfig + `  - allOf:
+    - path: [components, schemas, Bird]
+      mergeNonType: [required]
+`
+	txt := transformAll(t, "allof-discriminator-eg.yaml", config, true)
+	if got, want := txt, transformAll(t, "allof-discriminator-eg.yaml", config, false); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	contains(t, 4, txt, `
+Pet:
+  type: object
+  required:
+    - name
+  properties:
+`)
+}
+
+func TestAllOfParallel(t *testing.T) {
+	// The allOf transform modifies schemas that may be shared between
+	// the nodes being visited and so must not walk concurrently.
+	transform := func(ctx context.Context) string {
+		doc, cfg := loadForTest("allof-parallel-eg.yaml", `configs:
+  - allOf:
+    - path: [components, schemas, "re:Bird|Cat|Dog|Fish"]
+      mergeNonType: [required]
+`)
+		if err := cfg.ConfigureAll(); err != nil {
+			t.Fatal(err)
+		}
+		doc, err := transforms.Get("allOf").TransformContext(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return asYAML(t, doc)
+	}
+	ctx := context.Background()
+	if got, want := transform(transforms.WithParallel(ctx, 4)), transform(ctx); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -31,6 +31,20 @@ func (c Config) ConfigureAll() error {
 	return nil
 }
 
+// Transformers returns the transformers, in the order in which they
+// are listed in the configuration, for use with TransformAll.
+func (c Config) Transformers() ([]T, error) {
+	tfrs := make([]T, len(c.Transforms))
+	for i, name := range c.Transforms {
+		tfr, ok := installed[name]
+		if !ok {
+			return nil, fmt.Errorf("transformer %v not installed", name)
+		}
+		tfrs[i] = tfr
+	}
+	return tfrs, nil
+}
+
 // LoadConfigFile loads the transform configuration from the
 // specified YAML file.
 func LoadConfigFile(filename string) (Config, error) {
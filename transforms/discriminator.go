@@ -5,6 +5,7 @@
 package transforms
 
 import (
+	"context"
 	"strings"
 
 	"cloudeng.io/text/linewrap"
@@ -21,6 +22,7 @@ type discriminatorRule struct {
 	PathPrefix     []string `yaml:"pathPrefix,flow"`
 	CreateProperty bool     `yaml:"createProperty"`
 	CreateRequired bool     `yaml:"createRequired"`
+	prefix         openapi.Pattern
 }
 
 type discriminatorTransformer struct {
@@ -36,6 +38,13 @@ func (t *discriminatorTransformer) Configure(node yaml.Node) error {
 	if err := node.Decode(&dr); err != nil {
 		return err
 	}
+	for i, r := range dr {
+		p, err := openapi.NewPattern(r.PathPrefix...)
+		if err != nil {
+			return err
+		}
+		dr[i].prefix = p
+	}
 	t.DiscriminatorRules = dr
 	return nil
 }
@@ -52,8 +61,17 @@ The discriminator transform handles cases where a oneOf or anyOf specification i
 }
 
 func (t *discriminatorTransformer) Transform(doc *openapi3.T) (*openapi3.T, error) {
-	walker := openapi.NewWalker(t.visitor)
-	return doc, walker.Walk(doc)
+	return t.TransformContext(context.Background(), doc)
+}
+
+func (t *discriminatorTransformer) TransformContext(ctx context.Context, doc *openapi3.T) (*openapi3.T, error) {
+	walker := openapi.NewTypedWalker(openapi.TypedVisitor{Schema: t.visitor}, walkerOptions(ctx)...)
+	return doc, walk(ctx, walker, doc)
+}
+
+// Visitor implements Walker.
+func (t *discriminatorTransformer) Visitor() openapi.MultiVisitor {
+	return openapi.TypedVisitor{Schema: t.visitor}.MultiVisitor()
 }
 
 func (t *discriminatorTransformer) handleProperty(dr discriminatorRule, schema *openapi3.Schema) {
@@ -89,16 +107,15 @@ func (t *discriminatorTransformer) handleRequired(dr discriminatorRule, schema *
 	schema.Required = append(schema.Required, discName)
 }
 
-func (t *discriminatorTransformer) visitor(path []string, parent, node any) (bool, error) {
-	schema, ok := node.(*openapi3.SchemaRef)
-	if !ok {
-		return true, nil
-	}
+func (t *discriminatorTransformer) visitor(path []string, parent any, schema *openapi3.SchemaRef) (bool, error) {
 	if schema.Value.Discriminator == nil {
 		return true, nil
 	}
 
 	for _, dr := range t.DiscriminatorRules {
+		if !dr.prefix.MatchPrefix(path) {
+			continue
+		}
 		t.handleProperty(dr, schema.Value)
 		t.handleRequired(dr, schema.Value)
 	}
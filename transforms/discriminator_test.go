@@ -5,6 +5,7 @@
 package transforms_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/cosnicolaou/openapi/transforms"
@@ -43,3 +44,54 @@ Pet:
     propertyName: pet_type
 `)
 }
+
+func TestTransformAll(t *testing.T) {
+	config := discrimatorfConfig + `  - rewrites:
+    - path: [components, schemas, Pet, properties, pet_type]
+      rewrite: "/^string$/integer/"
+      replace: type
+`
+	transform := func(all bool) string {
+		return transformAll(t, "discriminator-eg.yaml", config, all)
+	}
+	txt := transform(true)
+	if got, want := txt, transform(false); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	contains(t, 4, txt, `
+Pet:
+  type: object
+  required:
+    - pet_type
+  properties:
+    pet_type:
+      type: integer
+`)
+}
+
+// transformAll applies the transformers configured by config to filename
+// either using TransformAll or by applying each of them in turn.
+func transformAll(t *testing.T, filename, config string, all bool) string {
+	doc, cfg := loadForTest(filename, config)
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	tfrs, err := cfg.Transformers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if all {
+		doc, err = transforms.TransformAll(ctx, doc, tfrs...)
+	} else {
+		for _, tfr := range tfrs {
+			if doc, err = tfr.TransformContext(ctx, doc); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return asYAML(t, doc)
+}
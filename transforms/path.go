@@ -1,29 +0,0 @@
-// Copyright 2022 Cosmos Nicolaou. All rights reserved.
-// Use of this source code is governed by the Apache-2.0
-// license that can be found in the LICENSE file.
-
-package transforms
-
-func match(a, b []string) bool {
-	if len(b) != len(a) {
-		return false
-	}
-	for i := range b {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
-}
-
-func prefix(a, b []string) bool {
-	if len(b) > len(a) {
-		return false
-	}
-	for i := range b {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
-}
@@ -5,6 +5,9 @@
 package transforms
 
 import (
+	"context"
+
+	"github.com/cosnicolaou/openapi"
 	"github.com/getkin/kin-openapi/openapi3"
 	"gopkg.in/yaml.v3"
 )
@@ -15,7 +18,69 @@ type T interface {
 	Name() string
 	Describe(node yaml.Node) string
 	Configure(node yaml.Node) error
+	// Transform is equivalent to TransformContext(context.Background(), doc).
 	Transform(*openapi3.T) (*openapi3.T, error)
+	// TransformContext applies the transform to the supplied document.
+	// The transform is abandoned, returning ctx.Err(), if the context
+	// is cancelled.
+	TransformContext(context.Context, *openapi3.T) (*openapi3.T, error)
+}
+
+// Walker is implemented by transformers that transform a document using
+// a single walk over it that transforms each node independently of any
+// other, and that can hence share that walk with other such transformers,
+// see TransformAll. Transformers that modify or depend on nodes other
+// than the one being visited, eg. the schemas referred to by that node,
+// must not implement Walker since the result of sharing a walk would
+// then depend on the order in which the nodes are visited rather than
+// the order of the transformers.
+type Walker interface {
+	T
+	// Visitor returns the visitor used to walk the document.
+	Visitor() openapi.MultiVisitor
+}
+
+// TransformAll applies the supplied transformers, in order, to doc.
+// Consecutive transformers that implement Walker share a single walk of
+// the document, with each node being visited by each of them in turn.
+// Any queries used by such transformers are evaluated against the
+// document before that walk. All other transformers, eg. allOf, which
+// modifies the schemas referred to by the allOf entries it visits, are
+// applied on their own, via their TransformContext methods, once the
+// preceding transformers have been applied, so that the order in which
+// changes are made is always that of the transformers.
+func TransformAll(ctx context.Context, doc *openapi3.T, transformers ...T) (*openapi3.T, error) {
+	var visitors []openapi.MultiVisitor
+	walkAll := func() error {
+		if len(visitors) == 0 {
+			return nil
+		}
+		walker := openapi.NewMultiWalker(visitors, walkerOptions(ctx)...)
+		visitors = nil
+		return walk(ctx, walker, doc)
+	}
+	for _, t := range transformers {
+		if w, ok := t.(Walker); ok {
+			if p, ok := t.(preparer); ok {
+				if err := p.prepare(ctx, doc); err != nil {
+					return nil, err
+				}
+			}
+			visitors = append(visitors, w.Visitor())
+			continue
+		}
+		if err := walkAll(); err != nil {
+			return nil, err
+		}
+		var err error
+		if doc, err = t.TransformContext(ctx, doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := walkAll(); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
 var installed = map[string]T{}
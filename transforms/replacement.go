@@ -5,6 +5,7 @@
 package transforms
 
 import (
+	"context"
 	"strings"
 
 	"cloudeng.io/text/linewrap"
@@ -19,8 +20,10 @@ func init() {
 
 type replacements struct {
 	Path        []string `yaml:",flow"`
+	Query       string   `yaml:"query,omitempty"`
 	Replacement yaml.Node
 	replacement map[string]any
+	selector    selector
 }
 
 type replacementTransformer struct {
@@ -41,6 +44,11 @@ func (t *replacementTransformer) Configure(node yaml.Node) error {
 		if err := r.Replacement.Decode(&rw[i].replacement); err != nil {
 			return err
 		}
+		sel, err := newSelector(r.Path, r.Query)
+		if err != nil {
+			return err
+		}
+		rw[i].selector = sel
 	}
 	return nil
 }
@@ -58,13 +66,25 @@ the specification need to be replaced with new ones.`))
 }
 
 func (t *replacementTransformer) Transform(doc *openapi3.T) (*openapi3.T, error) {
-	walker := openapi.NewWalker(t.visitor)
-	return doc, walker.Walk(doc)
+	return t.TransformContext(context.Background(), doc)
+}
+
+func (t *replacementTransformer) TransformContext(ctx context.Context, doc *openapi3.T) (*openapi3.T, error) {
+	for i := range t.ReplacementRules {
+		if err := t.ReplacementRules[i].selector.prepare(ctx, doc); err != nil {
+			return nil, err
+		}
+	}
+	// Replacements modify the parent of the node being visited and
+	// hence cannot be made concurrently.
+	opts := append(walkerOptions(ctx), openapi.WalkerParallel(1))
+	walker := openapi.NewWalker(t.visitor, opts...)
+	return doc, walk(ctx, walker, doc)
 }
 
 func (t *replacementTransformer) visitor(path []string, parent, node any) (bool, error) {
 	for _, repl := range t.ReplacementRules {
-		if !match(path, repl.Path) {
+		if !repl.selector.match(path) {
 			continue
 		}
 		pmap := jsonMap(parent)
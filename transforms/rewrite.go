@@ -5,6 +5,7 @@
 package transforms
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -20,10 +21,12 @@ func init() {
 }
 
 type rewriteRule struct {
-	Path    []string `yaml:"path,flow"`
-	Rewrite string
-	Replace string
-	repl    Replacement
+	Path     []string `yaml:"path,flow"`
+	Query    string   `yaml:"query,omitempty"`
+	Rewrite  string
+	Replace  string
+	repl     Replacement
+	selector selector
 }
 
 type rewriteTransformer struct {
@@ -37,6 +40,11 @@ func (t *rewriteTransformer) configure(rewrites []rewriteRule) ([]rewriteRule, e
 			return nil, err
 		}
 		rewrites[i].repl = repl
+		sel, err := newSelector(rw.Path, rw.Query)
+		if err != nil {
+			return nil, err
+		}
+		rewrites[i].selector = sel
 	}
 	return rewrites, nil
 }
@@ -68,8 +76,33 @@ The rewrites transform rewriting of fields using expressions of the form "/regex
 }
 
 func (t *rewriteTransformer) Transform(doc *openapi3.T) (*openapi3.T, error) {
-	walker := openapi.NewWalker(t.visitor)
-	return doc, walker.Walk(doc)
+	return t.TransformContext(context.Background(), doc)
+}
+
+func (t *rewriteTransformer) TransformContext(ctx context.Context, doc *openapi3.T) (*openapi3.T, error) {
+	if err := t.prepare(ctx, doc); err != nil {
+		return nil, err
+	}
+	walker := openapi.NewWalker(t.visitor, walkerOptions(ctx)...)
+	return doc, walk(ctx, walker, doc)
+}
+
+func (t *rewriteTransformer) prepare(ctx context.Context, doc *openapi3.T) error {
+	for i := range t.Rewrites {
+		if err := t.Rewrites[i].selector.prepare(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Visitor implements Walker.
+func (t *rewriteTransformer) Visitor() openapi.MultiVisitor {
+	return openapi.MultiVisitor{
+		Visit: func(_ context.Context, path []string, parent, node any) (bool, error) {
+			return t.visitor(path, parent, node)
+		},
+	}
 }
 
 func jsonMap(v any) map[string]any {
@@ -80,6 +113,14 @@ func jsonMap(v any) map[string]any {
 }
 
 func marshalMap(in map[string]any, out any) error {
+	if m, ok := out.(map[string]any); ok {
+		// Generic maps, eg. the values of extensions, are passed by value
+		// and hence are updated in place.
+		for k, v := range in {
+			m[k] = v
+		}
+		return nil
+	}
 	buf, err := json.Marshal(in)
 	if err != nil {
 		return err
@@ -92,13 +133,13 @@ func (t *rewriteTransformer) visitor(path []string, parent, node any) (bool, err
 		if len(rw.Replace) == 0 {
 			continue
 		}
-		if !match(path, rw.Path) {
+		if !rw.selector.match(path) {
 			continue
 		}
 		fields := jsonMap(node)
 		ov, ok := fields[rw.Replace].(string)
 		if !ok {
-			return false, fmt.Errorf("%v:%v is not a string\n", strings.Join(path, ":"), rw.Replace)
+			return false, fmt.Errorf("%v is not a string", rw.Replace)
 		}
 		if !rw.repl.MatchString(ov) {
 			continue
@@ -106,7 +147,7 @@ func (t *rewriteTransformer) visitor(path []string, parent, node any) (bool, err
 		fields[rw.Replace] = rw.repl.ReplaceAllString(ov)
 		if err := marshalMap(fields, node); err != nil {
 			fields[rw.Replace] = ov
-			return false, fmt.Errorf("%v:%v failed to update new value: %v\n", strings.Join(path, ":"), rw.Replace, err)
+			return false, fmt.Errorf("%v: failed to update new value: %v", rw.Replace, err)
 		}
 	}
 	return true, nil
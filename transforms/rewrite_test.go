@@ -5,11 +5,16 @@
 package transforms_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/cosnicolaou/openapi"
 	"github.com/cosnicolaou/openapi/transforms"
 	"github.com/getkin/kin-openapi/openapi3"
 	"gopkg.in/yaml.v3"
@@ -99,3 +104,224 @@ start:
   example: example_replacement
 `)
 }
+
+const rewritePatternConfig = `configs:
+  - rewrites:
+    - path: [components, schemas, "*", properties, "re:(start|end|id)"]
+      rewrite: "/^example_replacement$/something-new/"
+      replace: example
+    - path: ["**", date]
+      rewrite: "/^date-time$/date/"
+      replace: format
+ `
+
+func TestRewritePatterns(t *testing.T) {
+	doc, cfg := loadForTest("rewrite-eg.yaml", rewritePatternConfig)
+	tr := transforms.Get("rewrites")
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := tr.Transform(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := asYAML(t, doc)
+	contains(t, 8, txt, `
+date:
+  type: string
+  format: date
+  example: Site
+  maxLength: 255
+datetime:
+  type: string
+  format: date
+  example: Site
+  maxLength: 255
+end:
+  type: integer_error
+  example: something-new
+id:
+  type: string
+  example: something-new
+name:
+  type: string
+  maxLength: 255
+start:
+  type: integer
+  example: something-new
+`)
+}
+
+const rewriteQueryConfig = `configs:
+  - rewrites:
+    - query: "$.components.schemas.*.properties[?@.type == 'integer']"
+      rewrite: "/^example_replacement$/something-new/"
+      replace: example
+ `
+
+func TestRewriteQuery(t *testing.T) {
+	doc, cfg := loadForTest("rewrite-eg.yaml", rewriteQueryConfig)
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := transforms.Get("rewrites").Transform(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := asYAML(t, doc)
+	contains(t, 8, txt, `
+end:
+  type: integer_error
+  example: example_replacement
+id:
+  type: string
+  example: example_replacement
+name:
+  type: string
+  maxLength: 255
+start:
+  type: integer
+  example: something-new
+`)
+
+	_, cfg = loadForTest("rewrite-eg.yaml", `configs:
+  - rewrites:
+    - path: [components]
+      query: "$.components"
+      rewrite: "/a/b/"
+      replace: example
+ `)
+	err = cfg.ConfigureAll()
+	if err == nil || !strings.Contains(err.Error(), "only one of path or query may be specified") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestRewriteQueryParameters(t *testing.T) {
+	// Queries select parameters by name and extensions under "extensions",
+	// as for the paths of rules.
+	doc, cfg := loadForTest("parameters-eg.yaml", `configs:
+  - rewrites:
+    - query: "$.paths['/pets'].get.parameters.limit.schema"
+      rewrite: "/^integer_error$/integer/"
+      replace: type
+    - query: "$.paths['/pets'].get.parameters.limit.schema.extensions['x-rate']"
+      rewrite: "/^second_error$/second/"
+      replace: unit
+`)
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := transforms.WithParallel(context.Background(), 4)
+	doc, err := transforms.Get("rewrites").TransformContext(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := doc.Paths["/pets"].Get.Parameters[0].Value.Schema.Value
+	if got, want := schema.Type, "integer"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := schema.Extensions["x-rate"], map[string]any{"unit": "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRewriteErrors(t *testing.T) {
+	doc, cfg := loadForTest("rewrite-eg.yaml", `configs:
+  - rewrites:
+    - path: [components, schemas, api, properties, name]
+      rewrite: "/255/256/"
+      replace: maxLength
+ `)
+	tr := transforms.Get("rewrites")
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := tr.Transform(doc)
+	if err == nil || err.Error() != "/components/schemas/api/properties/name: maxLength is not a string" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestTransformContext(t *testing.T) {
+	doc, cfg := loadForTest("rewrite-eg.yaml", rewriteConfig)
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, name := range transforms.List() {
+		_, err := transforms.Get(name).TransformContext(ctx, doc)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: unexpected or missing error: %v", name, err)
+		}
+	}
+}
+
+func TestTransformPositions(t *testing.T) {
+	filename := filepath.Join("testdata", "rewrite-eg.yaml")
+	doc, cfg := loadForTest("rewrite-eg.yaml", `configs:
+  - rewrites:
+    - path: [components, schemas, api, properties, name]
+      rewrite: "/255/256/"
+      replace: maxLength
+ `)
+	if err := cfg.ConfigureAll(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := openapi.ReadSource(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := transforms.WithSource(context.Background(), src)
+	_, err = transforms.Get("rewrites").TransformContext(ctx, doc)
+	if got, want := fmt.Sprint(err), filename+":18:9: /components/schemas/api/properties/name: maxLength is not a string"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRewriteParameters(t *testing.T) {
+	// Parameters are identified by name in the paths of rules, but by
+	// their index in the JSON Pointers reported in errors.
+	transform := func(replace string) (*openapi3.T, error) {
+		doc, cfg := loadForTest("parameters-eg.yaml", `configs:
+  - rewrites:
+    - path: [paths, /pets, get, parameters, limit, schema]
+      rewrite: "/^integer_error$/integer/"
+      replace: `+replace+`
+`)
+		if err := cfg.ConfigureAll(); err != nil {
+			t.Fatal(err)
+		}
+		return transforms.Get("rewrites").TransformContext(context.Background(), doc)
+	}
+	doc, err := transform("type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Paths["/pets"].Get.Parameters[0].Value.Schema.Value.Type, "integer"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	_, err = transform("maxLength")
+	if got, want := fmt.Sprint(err), "/paths/~1pets/get/parameters/0/schema: maxLength is not a string"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTransformParallel(t *testing.T) {
+	transform := func(ctx context.Context) string {
+		doc, cfg := loadForTest("rewrite-eg.yaml", rewritePatternConfig)
+		if err := cfg.ConfigureAll(); err != nil {
+			t.Fatal(err)
+		}
+		doc, err := transforms.Get("rewrites").TransformContext(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return asYAML(t, doc)
+	}
+	ctx := context.Background()
+	if got, want := transform(transforms.WithParallel(ctx, 4)), transform(ctx); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
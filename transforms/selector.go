@@ -0,0 +1,67 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package transforms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/cosnicolaou/openapi/query"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// selector selects the nodes that a transformation rule applies to
+// using either a path pattern or a JSONPath query. Queries are evaluated
+// against the document before it is transformed.
+type selector struct {
+	pattern openapi.Pattern
+	query   *query.Query
+	paths   map[string]bool
+}
+
+func newSelector(path []string, expr string) (selector, error) {
+	if len(expr) > 0 {
+		if len(path) > 0 {
+			return selector{}, fmt.Errorf("only one of path or query may be specified")
+		}
+		q, err := query.Parse(expr)
+		return selector{query: q}, err
+	}
+	p, err := openapi.NewPattern(path...)
+	return selector{pattern: p}, err
+}
+
+// prepare evaluates the selector's query, if any, against doc. The query
+// is evaluated using the same walker options as the transformers so that
+// the paths it selects are those that they visit.
+func (s *selector) prepare(ctx context.Context, doc *openapi3.T) error {
+	if s.query == nil {
+		return nil
+	}
+	matches, err := s.query.Select(ctx, doc, walkerOptions(ctx)...)
+	if err != nil {
+		return err
+	}
+	s.paths = make(map[string]bool, len(matches))
+	for _, m := range matches {
+		s.paths[strings.Join(m.Path, "\x00")] = true
+	}
+	return nil
+}
+
+func (s selector) match(path []string) bool {
+	if s.query != nil {
+		return s.paths[strings.Join(path, "\x00")]
+	}
+	return s.pattern.Match(path)
+}
+
+// preparer is implemented by transformers whose rules use selectors
+// that must be prepared before the document is walked.
+type preparer interface {
+	prepare(ctx context.Context, doc *openapi3.T) error
+}
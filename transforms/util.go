@@ -5,14 +5,66 @@
 package transforms
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
 	"gopkg.in/yaml.v3"
 )
 
+type sourceKey struct{}
+
+// WithSource returns a context that carries the source of the document
+// to be transformed. Errors returned by TransformContext when called
+// with such a context include the position in that source of the node
+// that the error relates to.
+func WithSource(ctx context.Context, src *openapi.Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+type parallelKey struct{}
+
+// WithParallel returns a context that requests that the transformers
+// walk the document using n goroutines, see openapi.WalkerParallel.
+// Transformers that modify the containers of the nodes they visit,
+// such as the replacement transformer, or that modify schemas which may
+// be shared with other nodes, such as the allOf transformer, always walk
+// sequentially.
+func WithParallel(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelKey{}, n)
+}
+
+// walkerOptions returns the options used for the walkers created by
+// all of the transformers. In particular, the paths in errors are
+// reported as JSON Pointers to the nodes' definitions so that they can
+// be used directly in $refs and JSON Patch files, whereas the paths
+// matched by the transformers' rules are unchanged, eg. parameters are
+// identified by name.
+func walkerOptions(ctx context.Context) []openapi.WalkerOption {
+	opts := []openapi.WalkerOption{openapi.WalkerReportJSONPointers(true)}
+	if src, ok := ctx.Value(sourceKey{}).(*openapi.Source); ok {
+		opts = append(opts, openapi.WalkerSource(src))
+	}
+	if n, ok := ctx.Value(parallelKey{}).(int); ok {
+		opts = append(opts, openapi.WalkerParallel(n))
+	}
+	return opts
+}
+
+// walk walks doc using walker, having first decoded any OpenAPI 3.1
+// constructs that it contains, see openapi.Decode31, so that the changes
+// made to them by the transformers are retained.
+func walk(ctx context.Context, walker openapi.Walker, doc *openapi3.T) error {
+	if err := openapi.Decode31(ctx, doc); err != nil {
+		return err
+	}
+	return walker.WalkContext(ctx, doc)
+}
+
 func formatJSON(t any) string {
 	buf, _ := json.MarshalIndent(t, "", " ")
 	return string(buf)
@@ -0,0 +1,162 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// TypedVisitor provides callbacks for specific kinds of node in an
+// openapi3 document. Callbacks that are nil are not called and the
+// walker will skip any parts of the document that cannot contain a node
+// for which a callback is defined. The callbacks follow the same
+// conventions as Visitor and hence may return SkipNode or SkipAll.
+type TypedVisitor struct {
+	Schema         func(path []string, parent any, schema *openapi3.SchemaRef) (bool, error)
+	PathItem       func(path []string, parent any, pathItem *openapi3.PathItem) (bool, error)
+	Operation      func(path []string, parent any, op *openapi3.Operation) (bool, error)
+	Parameter      func(path []string, parent any, param *openapi3.ParameterRef) (bool, error)
+	RequestBody    func(path []string, parent any, body *openapi3.RequestBodyRef) (bool, error)
+	Response       func(path []string, parent any, resp *openapi3.ResponseRef) (bool, error)
+	Header         func(path []string, parent any, header *openapi3.HeaderRef) (bool, error)
+	SecurityScheme func(path []string, parent any, scheme *openapi3.SecuritySchemeRef) (bool, error)
+}
+
+// NewTypedWalker returns a Walker that will call the callbacks defined
+// in tv for the corresponding nodes in an openapi3 document.
+func NewTypedWalker(tv TypedVisitor, opts ...WalkerOption) Walker {
+	w := NewWalker(tv.visit, opts...).(*nodeWalker)
+	w.prune = tv.prune()
+	return w
+}
+
+// MultiVisitor returns a MultiVisitor, for use with NewMultiWalker,
+// that calls the callbacks defined in tv and prunes the walk in the
+// same way as NewTypedWalker.
+func (tv TypedVisitor) MultiVisitor() MultiVisitor {
+	return MultiVisitor{
+		Visit: Visitor(tv.visit).withContext(),
+		Prune: tv.prune(),
+	}
+}
+
+func (tv TypedVisitor) prune() func(node any) bool {
+	kinds := tv.kinds()
+	return func(node any) bool {
+		return kinds&reachable(node) == 0
+	}
+}
+
+func (tv TypedVisitor) visit(path []string, parent, node any) (bool, error) {
+	switch n := node.(type) {
+	case *openapi3.SchemaRef:
+		if tv.Schema != nil {
+			return tv.Schema(path, parent, n)
+		}
+	case *openapi3.PathItem:
+		if tv.PathItem != nil {
+			return tv.PathItem(path, parent, n)
+		}
+	case *openapi3.Operation:
+		if tv.Operation != nil {
+			return tv.Operation(path, parent, n)
+		}
+	case *openapi3.ParameterRef:
+		if tv.Parameter != nil {
+			return tv.Parameter(path, parent, n)
+		}
+	case *openapi3.RequestBodyRef:
+		if tv.RequestBody != nil {
+			return tv.RequestBody(path, parent, n)
+		}
+	case *openapi3.ResponseRef:
+		if tv.Response != nil {
+			return tv.Response(path, parent, n)
+		}
+	case *openapi3.HeaderRef:
+		if tv.Header != nil {
+			return tv.Header(path, parent, n)
+		}
+	case *openapi3.SecuritySchemeRef:
+		if tv.SecurityScheme != nil {
+			return tv.SecurityScheme(path, parent, n)
+		}
+	}
+	return true, nil
+}
+
+// nodeKinds is a bitmask of the kinds of node supported by TypedVisitor.
+type nodeKinds uint
+
+const (
+	schemaKind nodeKinds = 1 << iota
+	pathItemKind
+	operationKind
+	parameterKind
+	requestBodyKind
+	responseKind
+	headerKind
+	securitySchemeKind
+
+	allKinds nodeKinds = 1<<iota - 1
+)
+
+func (tv TypedVisitor) kinds() nodeKinds {
+	var k nodeKinds
+	for _, f := range []struct {
+		defined bool
+		kind    nodeKinds
+	}{
+		{tv.Schema != nil, schemaKind},
+		{tv.PathItem != nil, pathItemKind},
+		{tv.Operation != nil, operationKind},
+		{tv.Parameter != nil, parameterKind},
+		{tv.RequestBody != nil, requestBodyKind},
+		{tv.Response != nil, responseKind},
+		{tv.Header != nil, headerKind},
+		{tv.SecurityScheme != nil, securitySchemeKind},
+	} {
+		if f.defined {
+			k |= f.kind
+		}
+	}
+	return k
+}
+
+// reachable returns the kinds of node that may be visited when walking
+// the supplied node and its children.
+func reachable(node any) nodeKinds {
+	switch node.(type) {
+	case *openapi3.Paths, *openapi3.PathItem, *openapi3.Operation:
+		// Operations may contain callbacks and hence path items.
+		return allKinds &^ securitySchemeKind
+	case *openapi3.Callbacks, *openapi3.CallbackRef:
+		// The path items of callbacks are walked by WalkerAllFields.
+		return allKinds &^ securitySchemeKind
+	case *openapi3.Parameters, *openapi3.ParametersMap, *openapi3.ParameterRef:
+		return schemaKind | parameterKind | headerKind
+	case *openapi3.RequestBodies, *openapi3.RequestBodyRef:
+		return schemaKind | requestBodyKind | headerKind
+	case *openapi3.Responses, *openapi3.ResponseRef:
+		return schemaKind | responseKind | headerKind
+	case *openapi3.Headers, *openapi3.HeaderRef:
+		return schemaKind | headerKind
+	case *openapi3.Parameter, *openapi3.Content, *openapi3.MediaType, *openapi3.Encoding:
+		return schemaKind | headerKind
+	case *openapi3.Schemas, openapi3.SchemaRefs, *openapi3.SchemaRefs, *openapi3.SchemaRef, openapi3.AdditionalProperties:
+		return schemaKind
+	case *openapi3.SecuritySchemes, *openapi3.SecuritySchemeRef:
+		return securitySchemeKind
+	case *openapi3.Info, *openapi3.Servers, *openapi3.Server, *openapi3.ServerVariable,
+		*openapi3.SecurityRequirements, openapi3.SecurityRequirement,
+		*openapi3.Tags, *openapi3.Tag, *openapi3.ExternalDocs,
+		*openapi3.Examples, *openapi3.ExampleRef,
+		*openapi3.Links, *openapi3.LinkRef,
+		*openapi3.SecurityScheme, *openapi3.OAuthFlows, *openapi3.OAuthFlow,
+		*openapi3.Discriminator:
+		return 0
+	}
+	return allKinds
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestTypedWalker(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	opts := []openapi.WalkerOption{openapi.WalkerOrder(openapi.SortedOrder)}
+
+	byType := map[string][]string{}
+	visitor := func(path []string, parent, node any) (bool, error) {
+		tn := fmt.Sprintf("%T", node)
+		byType[tn] = append(byType[tn], strings.Join(path, ":"))
+		return true, nil
+	}
+	if err := openapi.NewWalker(visitor, opts...).Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	record := func(path []string) (bool, error) {
+		paths = append(paths, strings.Join(path, ":"))
+		return true, nil
+	}
+	for _, tc := range []struct {
+		tv   openapi.TypedVisitor
+		node any
+	}{
+		{openapi.TypedVisitor{Schema: func(path []string, _ any, _ *openapi3.SchemaRef) (bool, error) {
+			return record(path)
+		}}, &openapi3.SchemaRef{}},
+		{openapi.TypedVisitor{PathItem: func(path []string, _ any, _ *openapi3.PathItem) (bool, error) {
+			return record(path)
+		}}, &openapi3.PathItem{}},
+		{openapi.TypedVisitor{Operation: func(path []string, _ any, _ *openapi3.Operation) (bool, error) {
+			return record(path)
+		}}, &openapi3.Operation{}},
+		{openapi.TypedVisitor{Parameter: func(path []string, _ any, _ *openapi3.ParameterRef) (bool, error) {
+			return record(path)
+		}}, &openapi3.ParameterRef{}},
+		{openapi.TypedVisitor{RequestBody: func(path []string, _ any, _ *openapi3.RequestBodyRef) (bool, error) {
+			return record(path)
+		}}, &openapi3.RequestBodyRef{}},
+		{openapi.TypedVisitor{Response: func(path []string, _ any, _ *openapi3.ResponseRef) (bool, error) {
+			return record(path)
+		}}, &openapi3.ResponseRef{}},
+		{openapi.TypedVisitor{Header: func(path []string, _ any, _ *openapi3.HeaderRef) (bool, error) {
+			return record(path)
+		}}, &openapi3.HeaderRef{}},
+		{openapi.TypedVisitor{SecurityScheme: func(path []string, _ any, _ *openapi3.SecuritySchemeRef) (bool, error) {
+			return record(path)
+		}}, &openapi3.SecuritySchemeRef{}},
+	} {
+		paths = nil
+		if err := openapi.NewTypedWalker(tc.tv, opts...).Walk(doc); err != nil {
+			t.Fatal(err)
+		}
+		tn := fmt.Sprintf("%T", tc.node)
+		if len(paths) == 0 {
+			t.Errorf("%v: no nodes visited", tn)
+		}
+		if got, want := paths, byType[tn]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tn, got, want)
+		}
+	}
+
+	// Make sure that subtrees that cannot contain a security scheme
+	// are not visited.
+	paths = nil
+	tv := openapi.TypedVisitor{
+		SecurityScheme: func(path []string, _ any, _ *openapi3.SecuritySchemeRef) (bool, error) {
+			return true, nil
+		},
+	}
+	post := func(path []string, parent, node any) (bool, error) {
+		return record(path)
+	}
+	if err := openapi.NewTypedWalker(tv, append(opts, openapi.WalkerPostVisit(post))...).Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths, []string{
+		"components:securitySchemes:apiKey",
+		"components:securitySchemes:oauth",
+		"components:securitySchemes",
+		"components",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -5,40 +5,28 @@
 package openapi
 
 import (
-	"bytes"
 	"encoding/json"
-
-	"github.com/getkin/kin-openapi/openapi3"
-	"gopkg.in/yaml.v3"
+	"strings"
 )
 
-func FormatV3(doc *openapi3.T, isYAML bool) ([]byte, error) {
-	// roundtrip to/from json to ensure corner cases are handled
-	// correctly. See: http://web.archive.org/web/20190603050330/http://ghodss.com/2014/the-right-way-to-handle-yaml-in-golang/
-	data, err := doc.MarshalJSON()
-	if !isYAML {
-		return data, err
+// AsYAML returns doc, which may be any value that can be marshaled as
+// JSON, as YAML with its keys sorted and using the specified indentation.
+// The style may be changed using FormatStyle, in which case indent is
+// used only if the style does not specify one; all other options are
+// ignored.
+func AsYAML(indent int, doc any, opts ...FormatOption) (string, error) {
+	var o formatOptions
+	for _, fn := range opts {
+		fn(&o)
 	}
-	var tmp any
-	if err := json.Unmarshal(data, &tmp); err != nil {
-		return nil, err
+	if o.style.Indent == 0 {
+		o.style.Indent = indent
 	}
-	out := &bytes.Buffer{}
-	enc := yaml.NewEncoder(out)
-	enc.SetIndent(2)
-	err = enc.Encode(tmp)
-	return out.Bytes(), err
-}
-
-func AsYAML(indent int, doc any) (string, error) {
 	data, err := json.Marshal(doc)
-	var tmp any
-	if err := json.Unmarshal(data, &tmp); err != nil {
+	if err != nil {
 		return "", err
 	}
-	out := &bytes.Buffer{}
-	enc := yaml.NewEncoder(out)
-	enc.SetIndent(indent)
-	err = enc.Encode(tmp)
+	out := &strings.Builder{}
+	err = o.style.write(out, rawJSON(data), nil, true)
 	return out.String(), err
 }
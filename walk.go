@@ -5,30 +5,56 @@
 package openapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
 // Walker represents the interface implemented by all walkers.
 type Walker interface {
+	// Walk is equivalent to WalkContext(context.Background(), doc).
 	Walk(doc *openapi3.T) error
+	// WalkContext walks the supplied document. The context is passed
+	// to ContextVisitors and the walk is stopped, returning ctx.Err(),
+	// if the context is cancelled.
+	WalkContext(ctx context.Context, doc *openapi3.T) error
 }
 
 type walkerOptions struct {
 	followRefs    bool
-	visitPrefixes [][]string
+	visitPrefixes []Pattern
 	applyPrefix   bool
+	err           error
 	trace         bool
+	traceWriter   io.Writer
+	traceDetails  bool
+	pointers      bool
+	reportPtrs    bool
+	order         Order
+	source        *Source
+	postVisitor   Visitor
+	cycleVisitor  Visitor
+	allFields     bool
+	parallel      int
 }
 
 // WalkerOption represents an option for use when creating a new walker.
 type WalkerOption func(o *walkerOptions)
 
 // WalkerFollowRefs controls wether the walker will follow $ref's
-// and flatten them in place.
+// and flatten them in place. Recursive schemas are visited only once
+// per path, see WalkerCycleVisitor. RefChain may be used to determine
+// the $refs followed to reach a node.
 func WalkerFollowRefs(v bool) WalkerOption {
 	return func(o *walkerOptions) {
 		o.followRefs = v
@@ -36,726 +62,1085 @@ func WalkerFollowRefs(v bool) WalkerOption {
 }
 
 // WalkerVisitPrefix adds a prefix that the walk should call the Visitor
-// function for. All other paths will be ignored.
+// function for. All other paths will be ignored. The prefix is interpreted
+// as a Pattern and hence may contain wildcards; Walk will return an
+// error if it is not a valid pattern.
 func WalkerVisitPrefix(path ...string) WalkerOption {
 	return func(o *walkerOptions) {
-		o.visitPrefixes = append(o.visitPrefixes, path)
+		p, err := NewPattern(path...)
+		if err != nil && o.err == nil {
+			o.err = err
+		}
+		o.visitPrefixes = append(o.visitPrefixes, p)
 		o.applyPrefix = true
 	}
 }
 
+// Order specifies the order in which the keys of maps are visited.
+type Order int
+
+const (
+	// MapOrder visits keys in Go's map iteration order, which is
+	// randomized and hence differs from run to run.
+	MapOrder Order = iota
+	// SortedOrder visits keys in lexicographic order.
+	SortedOrder
+	// SourceOrder visits keys in the order that they appear in the
+	// document's source as specified via WalkerSource. Keys that do not
+	// appear in the source, for example those added by a transform, are
+	// visited in lexicographic order after those that do. SortedOrder is
+	// used if no source is specified.
+	SourceOrder
+)
+
+// WalkerOrder controls the order in which the keys of every map
+// in the document are visited.
+func WalkerOrder(order Order) WalkerOption {
+	return func(o *walkerOptions) {
+		o.order = order
+	}
+}
+
+// WalkerSource specifies the source of the document being walked. It is
+// used for SourceOrder and to include the positions of nodes in the
+// errors returned by the walker. Visitors may use Source.Position to
+// obtain the position of the node being visited.
+func WalkerSource(src *Source) WalkerOption {
+	return func(o *walkerOptions) {
+		o.source = src
+	}
+}
+
+// WalkerPostVisit specifies a Visitor to be called after all of the
+// children of a node have been visited, ie. in post-order. It is called
+// with the same arguments as the Visitor passed to NewWalker was for
+// that node and can be used for bottom-up processing of a document.
+func WalkerPostVisit(v Visitor) WalkerOption {
+	return func(o *walkerOptions) {
+		o.postVisitor = v
+	}
+}
+
+// WalkerCycleVisitor specifies a Visitor to be called when a recursive
+// schema is encountered, that is, a schema that refers, directly or
+// indirectly, to itself. The node passed to the Visitor is the
+// *openapi3.SchemaRef whose value is already being visited. The
+// children of such a schema are not visited again since doing so would
+// never terminate. Recursive schemas can only be encountered when
+// WalkerFollowRefs is enabled.
+func WalkerCycleVisitor(v Visitor) WalkerOption {
+	return func(o *walkerOptions) {
+		o.cycleVisitor = v
+	}
+}
+
+// WalkerJSONPointers controls whether paths are formatted as RFC 6901
+// JSON Pointers, rather than being joined with ':', when traced and in
+// the PathErrors returned by the walker and the errors returned by
+// Cursor. The pointers refer to the nodes in the document itself, that
+// is, parameters are identified by their index rather than by name,
+// extensions are visited as fields of the nodes that they extend rather
+// than under "extensions" and nodes reached via a $ref are reported
+// at the path of their definition, see CanonicalPath. The paths passed
+// to visitors follow the same conventions, except for the latter, and
+// may be converted to pointers using CanonicalPath and JSONPointer.
+func WalkerJSONPointers(v bool) WalkerOption {
+	return func(o *walkerOptions) {
+		o.pointers = v
+	}
+}
+
+// WalkerReportJSONPointers controls whether paths are formatted as RFC
+// 6901 JSON Pointers when traced and in errors, as per WalkerJSONPointers,
+// but without changing the paths passed to visitors. That is, visitors
+// are passed parameters by name and extensions under "extensions" but
+// the pointers that are reported refer to the nodes in the document.
+func WalkerReportJSONPointers(v bool) WalkerOption {
+	return func(o *walkerOptions) {
+		o.reportPtrs = v
+	}
+}
+
+// WalkerTracePaths controls whether the path of every node is written
+// to the trace writer, os.Stderr by default, as it is visited.
 func WalkerTracePaths(v bool) WalkerOption {
 	return func(o *walkerOptions) {
 		o.trace = v
 	}
 }
 
+// WalkerTraceWriter specifies the writer that traces are written to.
+func WalkerTraceWriter(w io.Writer) WalkerOption {
+	return func(o *walkerOptions) {
+		o.traceWriter = w
+	}
+}
+
+// WalkerTraceDetails controls whether traces include the type of each
+// node, its $ref, if any, and the time taken by the Visitor for it.
+func WalkerTraceDetails(v bool) WalkerOption {
+	return func(o *walkerOptions) {
+		o.traceDetails = v
+	}
+}
+
 // Visitor is called for every node in the walk. It returns true for the
 // walk to continue, false otherwise. The walk will stop when an error is
-// returned.
+// returned. A Visitor is called before any of the children of a node are
+// visited, see WalkerPostVisit for calling a function after its
+// children have been visited.
+//
+// The parent of a node is the innermost node that encloses it and that
+// was itself visited, or the document for top-level nodes. For example,
+// the parent of an operation is its *openapi3.PathItem and the parent of
+// a path item is the *openapi3.Paths map. Ancestors may be used to obtain
+// all of a node's ancestors.
+//
+// A Visitor may return SkipNode to skip the children of the current node,
+// but otherwise continue the walk, and SkipAll to stop the walk without
+// an error. Returning false is equivalent to returning SkipAll. Neither
+// is returned by Walk. Any other error stops the walk and is returned by
+// Walk wrapped in a PathError.
 type Visitor func(path []string, parent, node any) (ok bool, err error)
 
+// ContextVisitor is like Visitor, but is also passed the context that
+// was supplied to WalkContext.
+type ContextVisitor func(ctx context.Context, path []string, parent, node any) (ok bool, err error)
+
+func (v Visitor) withContext() ContextVisitor {
+	if v == nil {
+		return nil
+	}
+	return func(_ context.Context, path []string, parent, node any) (bool, error) {
+		return v(path, parent, node)
+	}
+}
+
+var (
+	// SkipNode is used as a return value from a Visitor to indicate
+	// that the children of the current node are not to be visited.
+	// The post-visitor, if any, is not called for the node.
+	SkipNode = errors.New("skip this node")
+
+	// SkipAll is used as a return value from a Visitor to indicate
+	// that the walk is to be stopped.
+	SkipAll = errors.New("skip everything and stop the walk")
+)
+
 // NewWalker returns a Walker that will visit every node in an openapi3 document.
 func NewWalker(v Visitor, opts ...WalkerOption) Walker {
+	return NewContextWalker(v.withContext(), opts...)
+}
+
+// NewContextWalker is like NewWalker except that the Visitor is also
+// passed the context supplied to WalkContext.
+func NewContextWalker(v ContextVisitor, opts ...WalkerOption) Walker {
 	w := &nodeWalker{visitor: v}
 	for _, opt := range opts {
 		opt(&w.opts)
 	}
+	w.postVisitor = w.opts.postVisitor.withContext()
+	w.cycleVisitor = w.opts.cycleVisitor.withContext()
 	return w
 }
 
 type nodeWalker struct {
-	opts    walkerOptions
-	visitor Visitor
+	opts         walkerOptions
+	visitor      ContextVisitor
+	postVisitor  ContextVisitor
+	cycleVisitor ContextVisitor
+	state        *walkState
+	// prune, if set, returns true for nodes that need not be visited.
+	prune func(node any) bool
+	// store31 is set by Apply, see walk31.go.
+	store31 bool
+}
+
+// walkState represents the state of a single walk.
+type walkState struct {
+	ctx context.Context
+	// schemas records the schemas on the path currently being visited
+	// and is used to detect recursive schemas.
+	schemas map[*openapi3.Schema]bool
+	// values records the values of the other $refs on the path currently
+	// being visited when walking all fields.
+	values map[any]bool
+	// ancestors records the nodes whose children are currently being
+	// visited, starting with the document itself.
+	ancestors []Ancestor
+	// refs records the $refs followed to reach the nodes currently
+	// being visited.
+	refs []Ref
+	// worker is true for the state of a worker walking a subtree
+	// concurrently, see WalkerParallel.
+	worker bool
+	// traceMu serializes the trace output of concurrent workers.
+	traceMu *sync.Mutex
+	// multi records the state of the visitors of a multi-walker.
+	multi *multiState
+	// decoded records the OpenAPI 3.1 constructs decoded during the
+	// walk, it is nil for swagger 2.0 documents, see walk31.go.
+	decoded *decoded31
+	// pointers is set if WalkerJSONPointers was specified.
+	pointers bool
+	// reportPtrs is set if WalkerReportJSONPointers was specified.
+	reportPtrs bool
 }
 
-// returns true if b is a prefix of a
-func prefixMatch(a, b []string) bool {
-	if len(b) > len(a) {
-		return false
+type walkStateKey struct{}
+
+// newWalkState returns the state for a new walk of root and a context,
+// derived from ctx, that carries that state.
+func newWalkState(ctx context.Context, root any, opts *walkerOptions) *walkState {
+	st := &walkState{
+		pointers:   opts.pointers,
+		reportPtrs: opts.reportPtrs,
+		schemas:    map[*openapi3.Schema]bool{},
+		values:     map[any]bool{},
+		ancestors:  []Ancestor{{Node: root}},
+		traceMu:    &sync.Mutex{},
+	}
+	st.ctx = context.WithValue(ctx, walkStateKey{}, st)
+	return st
+}
+
+// parent returns the innermost node whose children are being visited.
+func (st *walkState) parent() any {
+	return st.ancestors[len(st.ancestors)-1].Node
+}
+
+// walkStateFromContext returns the state of the walk that ctx was
+// passed to a visitor by.
+func walkStateFromContext(ctx context.Context) *walkState {
+	st, _ := ctx.Value(walkStateKey{}).(*walkState)
+	return st
+}
+
+// orderedKeys returns the keys of m in the order specified
+// by the walker's options.
+func orderedKeys[V any](o *walkerOptions, path []string, m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	switch {
+	case o.order == SourceOrder && o.source != nil:
+		o.source.sortKeys(path, keys)
+	case o.order == SortedOrder || o.order == SourceOrder:
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+func (wn nodeWalker) selected(path []string) bool {
+	if !wn.opts.applyPrefix {
+		return true
 	}
-	for i := range b {
-		if a[i] != b[i] {
-			return false
+	for _, p := range wn.opts.visitPrefixes {
+		if p.MatchPrefix(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// call calls the supplied visitor and interprets its results. It returns
+// descend as false if the children of the node are to be skipped and
+// ok as false if the walk is to be stopped.
+func (wn nodeWalker) call(v ContextVisitor, path []string, parent, node any) (descend, ok bool, err error) {
+	if v == nil || !wn.selected(path) {
+		return true, true, nil
+	}
+	ok, err = v(wn.state.ctx, path, parent, node)
+	switch {
+	case err == SkipNode:
+		return false, true, nil
+	case err == SkipAll:
+		return false, false, nil
+	case err != nil:
+		if _, ok := err.(*PathError); !ok {
+			err = wn.pathError(path, node, err)
 		}
+		return false, false, err
+	}
+	return ok, ok, nil
+}
+
+// pathError returns a PathError for err, returned for node. Node may be
+// nil if it is the innermost ancestor, see formatPath.
+func (wn nodeWalker) pathError(path []string, node any, err error) *PathError {
+	return &PathError{
+		Path:      append([]string{}, path...),
+		Pos:       wn.opts.source.Position(path),
+		Err:       err,
+		formatted: wn.state.formatPath(path, node),
 	}
-	return true
 }
 
-func (wn nodeWalker) visit(path []string, parent, node any) (ok bool, err error) {
+// node visits node, followed by its children, if any, and then calls
+// the post-visitor for node. The children of node are skipped if the
+// visitor returns SkipNode.
+func (wn nodeWalker) node(path []string, node any, children func() (bool, error)) (ok bool, err error) {
+	if wn.prune != nil && wn.prune(node) {
+		return true, nil
+	}
+	if err := wn.state.ctx.Err(); err != nil {
+		return false, err
+	}
+	start := time.Now()
+	parent := wn.state.parent()
+	descend, ok, err := wn.call(wn.visitor, path, parent, node)
 	if wn.opts.trace {
-		fmt.Println(strings.Join(path, ":"))
-	}
-	if wn.opts.applyPrefix {
-		match := false
-		for _, p := range wn.opts.visitPrefixes {
-			if prefixMatch(path, p) {
-				match = true
-				break
-			}
+		wn.trace(path, node, start)
+	}
+	if !descend {
+		return ok, err
+	}
+	if children != nil {
+		st := wn.state
+		st.ancestors = append(st.ancestors, Ancestor{Path: path, Node: node})
+		nrefs := len(st.refs)
+		if ref := RefOf(node); len(ref) > 0 {
+			st.refs = append(st.refs, Ref{Ref: ref, Path: append([]string{}, path...)})
 		}
-		if !match {
-			return true, nil
+		ok, err = children()
+		st.ancestors = st.ancestors[:len(st.ancestors)-1]
+		st.refs = st.refs[:nrefs]
+		if !ok || err != nil {
+			return
 		}
 	}
-	return wn.visitor(path, parent, node)
+	_, ok, err = wn.call(wn.postVisitor, path, parent, node)
+	return
 }
 
+func (wn nodeWalker) trace(path []string, node any, start time.Time) {
+	out := &strings.Builder{}
+	out.WriteString(wn.state.formatPath(path, node))
+	if wn.opts.traceDetails {
+		fmt.Fprintf(out, " %T", node)
+		if ref := RefOf(node); len(ref) > 0 {
+			fmt.Fprintf(out, " $ref=%s", ref)
+		}
+		fmt.Fprintf(out, " %v", time.Since(start))
+	}
+	out.WriteRune('\n')
+	w := wn.opts.traceWriter
+	if w == nil {
+		w = os.Stderr
+	}
+	wn.state.traceMu.Lock()
+	defer wn.state.traceMu.Unlock()
+	io.WriteString(w, out.String())
+}
+
+// visitLeaf visits a node that has no children.
+func (wn nodeWalker) visitLeaf(path []string, node any) (ok bool, err error) {
+	return wn.node(path, node, nil)
+}
+
+// Walk implements Walker.
 func (wn nodeWalker) Walk(doc *openapi3.T) error {
+	return wn.WalkContext(context.Background(), doc)
+}
+
+// WalkContext implements Walker.
+func (wn nodeWalker) WalkContext(ctx context.Context, doc *openapi3.T) error {
+	if wn.opts.err != nil {
+		return wn.opts.err
+	}
+	wn.state = newWalkState(ctx, doc, &wn.opts)
+	wn.state.decoded = newDecoded31(wn.store31)
+	if wn.opts.allFields {
+		_, err := wn.reflectChildren(nil, reflect.ValueOf(doc).Elem())
+		return err
+	}
+	_, err := wn.doc(doc)
+	return err
+}
+
+func (wn nodeWalker) doc(doc *openapi3.T) (ok bool, err error) {
 	if doc.Info != nil {
-		ok, err := wn.visit([]string{"info"}, doc, doc.Info)
-		if !ok || err != nil {
-			return err
+		if ok, err = wn.visitLeaf([]string{"info"}, doc.Info); !ok || err != nil {
+			return
 		}
 	}
-	ok, err := wn.components([]string{"components"}, doc, doc.Components)
-	if !ok || err != nil {
-		return err
+	if ok, err = wn.components([]string{"components"}, doc.Components); !ok || err != nil {
+		return
 	}
-	ok, err = wn.paths([]string{"paths"}, doc, &doc.Paths)
-	if err != nil {
-		return err
+	if ok, err = wn.paths([]string{"paths"}, &doc.Paths); !ok || err != nil {
+		return
 	}
-	if ok, err = wn.servers([]string{"servers"}, doc, &doc.Servers); !ok || err != nil {
-		return err
+	if ok, err = wn.webhooks([]string{"webhooks"}, doc); !ok || err != nil {
+		return
 	}
-	if ok, err = wn.securityReqs([]string{"security"}, doc, &doc.Security); !ok || err != nil {
-		return err
+	if ok, err = wn.servers([]string{"servers"}, &doc.Servers); !ok || err != nil {
+		return
 	}
-	if ok, err = wn.visit([]string{"externalDocs"}, doc, doc.ExternalDocs); !ok || err != nil {
-		return err
+	if ok, err = wn.securityReqs([]string{"security"}, &doc.Security); !ok || err != nil {
+		return
 	}
-	if ok, err = wn.tags([]string{"tags"}, doc, &doc.Tags); !ok || err != nil {
-		return err
+	if ok, err = wn.visitLeaf([]string{"externalDocs"}, doc.ExternalDocs); !ok || err != nil {
+		return
 	}
-	return nil
+	return wn.tags([]string{"tags"}, &doc.Tags)
 }
 
-func (wn nodeWalker) tags(path []string, parent any, tags *openapi3.Tags) (ok bool, err error) {
+func (wn nodeWalker) tags(path []string, tags *openapi3.Tags) (ok bool, err error) {
 	if tags == nil || len(*tags) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, tags); err != nil {
-		return
-	}
-	for i, tag := range *tags {
-		if ok, err = wn.tag(append(path, strconv.Itoa(i)), parent, tag); err != nil {
-			return
+	return wn.node(path, tags, func() (ok bool, err error) {
+		for i, tag := range *tags {
+			if ok, err = wn.tag(append(path, strconv.Itoa(i)), tag); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return false, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) tag(path []string, parent any, tag *openapi3.Tag) (ok bool, err error) {
+func (wn nodeWalker) tag(path []string, tag *openapi3.Tag) (ok bool, err error) {
 	if tag == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, tag); err != nil {
-		return
-	}
-	return wn.externalDocs(append(path, "externalDocs"), parent, tag.ExternalDocs)
+	return wn.node(path, tag, func() (ok bool, err error) {
+		return wn.externalDocs(append(path, "externalDocs"), tag.ExternalDocs)
+	})
 }
 
-func (wn nodeWalker) externalDocs(path []string, parent any, edocs *openapi3.ExternalDocs) (ok bool, err error) {
+func (wn nodeWalker) externalDocs(path []string, edocs *openapi3.ExternalDocs) (ok bool, err error) {
 	if edocs == nil {
 		return true, nil
 	}
-	return wn.visit(path, parent, edocs)
+	return wn.visitLeaf(path, edocs)
 }
 
-func (wn nodeWalker) securityReqs(path []string, parent any, reqs *openapi3.SecurityRequirements) (ok bool, err error) {
+func (wn nodeWalker) securityReqs(path []string, reqs *openapi3.SecurityRequirements) (ok bool, err error) {
 	if reqs == nil || len(*reqs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, reqs); err != nil {
-		return
-	}
-	for i, req := range *reqs {
-		if ok, err = wn.visit(append(path, strconv.Itoa(i)), reqs, req); err != nil {
-			return
+	return wn.node(path, reqs, func() (ok bool, err error) {
+		for i, req := range *reqs {
+			if ok, err = wn.visitLeaf(append(path, strconv.Itoa(i)), req); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) paths(path []string, parent any, paths *openapi3.Paths) (ok bool, err error) {
+func (wn nodeWalker) paths(path []string, paths *openapi3.Paths) (ok bool, err error) {
 	if paths == nil || len(*paths) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, paths); !ok || err != nil {
-		return
-	}
-	for p, pi := range *paths {
-		if ok, err = wn.pathItem(append(path, p), paths, pi); !ok || err != nil {
-			return
-		}
-	}
-	return true, nil
+	return wn.node(path, paths, func() (ok bool, err error) {
+		return wn.forEach(path, orderedKeys(&wn.opts, path, *paths), func(wn nodeWalker, path []string, p string) (bool, error) {
+			return wn.pathItem(path, (*paths)[p])
+		})
+	})
 }
 
-func (wn nodeWalker) pathItem(path []string, parent any, pi *openapi3.PathItem) (ok bool, err error) {
+func (wn nodeWalker) pathItem(path []string, pi *openapi3.PathItem) (ok bool, err error) {
 	if pi == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, pi); !ok || err != nil {
-		return
-	}
-	for _, ops := range []struct {
-		name string
-		op   *openapi3.Operation
-	}{
-		{"connect", pi.Connect},
-		{"delete", pi.Delete},
-		{"get", pi.Get},
-		{"head", pi.Head},
-		{"options", pi.Options},
-		{"patch", pi.Patch},
-		{"post", pi.Post},
-		{"put", pi.Put},
-		{"trace", pi.Trace},
-	} {
-		if ok, err = wn.operation(append(path, ops.name), pi, ops.op); !ok || err != nil {
+	return wn.node(path, pi, func() (ok bool, err error) {
+		for _, ops := range []struct {
+			name string
+			op   *openapi3.Operation
+		}{
+			{"connect", pi.Connect},
+			{"delete", pi.Delete},
+			{"get", pi.Get},
+			{"head", pi.Head},
+			{"options", pi.Options},
+			{"patch", pi.Patch},
+			{"post", pi.Post},
+			{"put", pi.Put},
+			{"trace", pi.Trace},
+		} {
+			if ok, err = wn.operation(append(path, ops.name), ops.op); !ok || err != nil {
+				return
+			}
+		}
+		if ok, err = wn.servers(append(path, "servers"), &pi.Servers); !ok || err != nil {
 			return
 		}
-	}
-	if ok, err = wn.servers(append(path, "servers"), pi, &pi.Servers); !ok || err != nil {
-		return
-	}
-	return wn.parameters(append(path, "parameters"), pi, &pi.Parameters)
+		return wn.parameters(append(path, "parameters"), &pi.Parameters)
+	})
 }
 
-func (wn nodeWalker) operation(path []string, parent any, op *openapi3.Operation) (ok bool, err error) {
+func (wn nodeWalker) operation(path []string, op *openapi3.Operation) (ok bool, err error) {
 	if op == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, op); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.parameters(append(path, "parameters"), op, &op.Parameters); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.servers(append(path, "servers"), op, op.Servers); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.responses(append(path, "responses"), op, &op.Responses); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.securityReqs(append(path, "security"), op, op.Security); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.requestBodyRef(append(path, "requestBody"), op, op.RequestBody); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.callbacks(append(path, "callbacks"), op, &op.Callbacks); !ok || err != nil {
-		return
-	}
-	return wn.externalDocs(append(path, "externalDocs"), op, op.ExternalDocs)
+	return wn.node(path, op, func() (ok bool, err error) {
+		if ok, err = wn.parameters(append(path, "parameters"), &op.Parameters); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.servers(append(path, "servers"), op.Servers); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.responses(append(path, "responses"), &op.Responses); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.securityReqs(append(path, "security"), op.Security); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.requestBodyRef(append(path, "requestBody"), op.RequestBody); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.callbacks(append(path, "callbacks"), &op.Callbacks); !ok || err != nil {
+			return
+		}
+		return wn.externalDocs(append(path, "externalDocs"), op.ExternalDocs)
+	})
 }
 
-func (wn nodeWalker) callbacks(path []string, parent any, cbs *openapi3.Callbacks) (ok bool, err error) {
+func (wn nodeWalker) callbacks(path []string, cbs *openapi3.Callbacks) (ok bool, err error) {
 	if cbs == nil || len(*cbs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, cbs); !ok || err != nil {
-		return
-	}
-	for n, cb := range *cbs {
-		if ok, err = wn.callbackRef(append(path, n), parent, cb); !ok || err != nil {
-			return
+	return wn.node(path, cbs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *cbs) {
+			cb := (*cbs)[n]
+			if ok, err = wn.callbackRef(append(path, n), cb); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) callbackRef(path []string, parent any, req *openapi3.CallbackRef) (ok bool, err error) {
+func (wn nodeWalker) callbackRef(path []string, req *openapi3.CallbackRef) (ok bool, err error) {
 	if req == nil || len(*req.Value) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, req); !ok || err != nil {
-		return
-	}
-	for n, pi := range *req.Value {
-		if ok, err = wn.visit(append(path, n), parent, pi); !ok || err != nil {
-			return
+	return wn.node(path, req, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *req.Value) {
+			pi := (*req.Value)[n]
+			if ok, err = wn.pathItem(append(path, n), pi); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) responses(path []string, parent any, resps *openapi3.Responses) (ok bool, err error) {
+func (wn nodeWalker) responses(path []string, resps *openapi3.Responses) (ok bool, err error) {
 	if resps == nil || len(*resps) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, resps); !ok || err != nil {
-		return
-	}
-	for n, r := range *resps {
-		if ok, err = wn.responseRef(append(path, n), parent, r); !ok || err != nil {
-			return
+	return wn.node(path, resps, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *resps) {
+			r := (*resps)[n]
+			if ok, err = wn.responseRef(append(path, n), r); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) responseRef(path []string, parent any, resp *openapi3.ResponseRef) (ok bool, err error) {
+func (wn nodeWalker) responseRef(path []string, resp *openapi3.ResponseRef) (ok bool, err error) {
 	if resp == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, resp); !ok || err != nil {
-		return
-	}
-	rv := resp.Value
-	if ok, err = wn.headers(append(path, "headers"), parent, &rv.Headers); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.content(append(path, "content"), parent, &rv.Content); !ok || err != nil {
-		return
-	}
-	return wn.links(append(path, "links"), parent, &resp.Value.Links)
+	return wn.node(path, resp, func() (ok bool, err error) {
+		rv := resp.Value
+		if ok, err = wn.headers(append(path, "headers"), &rv.Headers); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.content(append(path, "content"), &rv.Content); !ok || err != nil {
+			return
+		}
+		return wn.links(append(path, "links"), &resp.Value.Links)
+	})
 }
 
-func (wn nodeWalker) links(path []string, parent any, lnks *openapi3.Links) (ok bool, err error) {
+func (wn nodeWalker) links(path []string, lnks *openapi3.Links) (ok bool, err error) {
 	if lnks == nil || len(*lnks) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, lnks); !ok || err != nil {
-		return
-	}
-	for n, lnk := range *lnks {
-		if ok, err = wn.linkRef(append(path, n), parent, lnk); !ok || err != nil {
-			return
+	return wn.node(path, lnks, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *lnks) {
+			lnk := (*lnks)[n]
+			if ok, err = wn.linkRef(append(path, n), lnk); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) linkRef(path []string, parent any, lnk *openapi3.LinkRef) (ok bool, err error) {
+func (wn nodeWalker) linkRef(path []string, lnk *openapi3.LinkRef) (ok bool, err error) {
 	if lnk == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, lnk); !ok || err != nil {
-		return
-	}
-	for n, par := range lnk.Value.Parameters {
-		if ok, err = wn.visit(append(path, n), lnk, par); !ok || err != nil {
-			return
+	return wn.node(path, lnk, func() (ok bool, err error) {
+		ppath := append(path, "parameters")
+		for _, n := range orderedKeys(&wn.opts, ppath, lnk.Value.Parameters) {
+			if ok, err = wn.visitLeaf(append(ppath, n), lnk.Value.Parameters[n]); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return wn.server(append(path, "servers"), lnk, lnk.Value.Server)
+		return wn.server(append(path, "server"), lnk.Value.Server)
+	})
 }
 
-func (wn nodeWalker) servers(path []string, parent any, srvs *openapi3.Servers) (ok bool, err error) {
+func (wn nodeWalker) servers(path []string, srvs *openapi3.Servers) (ok bool, err error) {
 	if srvs == nil || len(*srvs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, srvs); !ok || err != nil {
-		return
-	}
-	for i, srv := range *srvs {
-		if ok, err = wn.server(append(path, strconv.Itoa(i)), parent, srv); !ok || err != nil {
-			return
+	return wn.node(path, srvs, func() (ok bool, err error) {
+		for i, srv := range *srvs {
+			if ok, err = wn.server(append(path, strconv.Itoa(i)), srv); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) server(path []string, parent any, s *openapi3.Server) (ok bool, err error) {
+func (wn nodeWalker) server(path []string, s *openapi3.Server) (ok bool, err error) {
 	if s == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, s); !ok || err != nil {
-		return
-	}
-	for v, sv := range s.Variables {
-		if ok, err = wn.visit(append(path, v), parent, sv); !ok || err != nil {
-			return
+	return wn.node(path, s, func() (ok bool, err error) {
+		vpath := append(path, "variables")
+		for _, v := range orderedKeys(&wn.opts, vpath, s.Variables) {
+			if ok, err = wn.visitLeaf(append(vpath, v), s.Variables[v]); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) parametersMap(path []string, parent any, pars *openapi3.ParametersMap) (ok bool, err error) {
+func (wn nodeWalker) parametersMap(path []string, pars *openapi3.ParametersMap) (ok bool, err error) {
 	if pars == nil || len(*pars) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, pars); !ok || err != nil {
-		return
-	}
-	for n, par := range *pars {
-		if ok, err = wn.parameterRef(append(path, n), parent, par); !ok || err != nil {
-			return
+	return wn.node(path, pars, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *pars) {
+			par := (*pars)[n]
+			if ok, err = wn.parameterRef(append(path, n), par); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
+}
+
+// paramName returns the path element for the i'th parameter in a list,
+// its name, or its index if it has no name, eg. a $ref that has not
+// been resolved, or if paths are reported as JSON Pointers since these
+// must refer to its position in the list.
+func (wn nodeWalker) paramName(i int, name string) string {
+	if len(name) == 0 || wn.opts.pointers {
+		return strconv.Itoa(i)
+	}
+	return name
 }
 
-func (wn nodeWalker) parameters(path []string, parent any, pars *openapi3.Parameters) (ok bool, err error) {
+func (wn nodeWalker) parameters(path []string, pars *openapi3.Parameters) (ok bool, err error) {
 	if pars == nil || len(*pars) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, pars); !ok || err != nil {
-		return
-	}
-	for _, par := range *pars {
-		if ok, err = wn.parameterRef(append(path, par.Value.Name), parent, par); !ok || err != nil {
-			return
+	return wn.node(path, pars, func() (ok bool, err error) {
+		for i, par := range *pars {
+			name := ""
+			if par != nil && par.Value != nil {
+				name = par.Value.Name
+			}
+			if ok, err = wn.parameterRef(append(path, wn.paramName(i, name)), par); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) parameter(path []string, parent any, par *openapi3.Parameter) (ok bool, err error) {
+func (wn nodeWalker) parameter(path []string, par *openapi3.Parameter) (ok bool, err error) {
 	if par == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, par); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.schemaRef(append(path, "schema"), parent, par.Schema); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.examples(append(path, "examples"), parent, &par.Examples); !ok || err != nil {
-		return
-	}
-	return wn.content(append(path, "content"), parent, &par.Content)
+	return wn.node(path, par, func() (ok bool, err error) {
+		if ok, err = wn.schemaRef(append(path, "schema"), par.Schema); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.examples(append(path, "examples"), &par.Examples); !ok || err != nil {
+			return
+		}
+		return wn.content(append(path, "content"), &par.Content)
+	})
 }
 
-func (wn nodeWalker) parameterRef(path []string, parent any, pr *openapi3.ParameterRef) (ok bool, err error) {
+func (wn nodeWalker) parameterRef(path []string, pr *openapi3.ParameterRef) (ok bool, err error) {
 	if pr == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, pr); !ok || err != nil {
-		return
-	}
-	prv := pr.Value
-	if ok, err = wn.schemaRef(append(path, "schema"), parent, prv.Schema); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.examples(append(path, "examples"), parent, &prv.Examples); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.content(append(path, "content"), parent, &prv.Content); !ok || err != nil {
-		return
-	}
-	return true, nil
+	return wn.node(path, pr, func() (ok bool, err error) {
+		prv := pr.Value
+		if ok, err = wn.schemaRef(append(path, "schema"), prv.Schema); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.examples(append(path, "examples"), &prv.Examples); !ok || err != nil {
+			return
+		}
+		return wn.content(append(path, "content"), &prv.Content)
+	})
 }
 
-func (wn nodeWalker) examples(path []string, parent any, egs *openapi3.Examples) (ok bool, err error) {
+func (wn nodeWalker) examples(path []string, egs *openapi3.Examples) (ok bool, err error) {
 	if egs == nil || len(*egs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, egs); !ok || err != nil {
-		return
-	}
-	for n, eg := range *egs {
-		if ok, err = wn.visit(append(path, n), parent, eg); !ok || err != nil {
-			return
+	return wn.node(path, egs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *egs) {
+			eg := (*egs)[n]
+			if ok, err = wn.visitLeaf(append(path, n), eg); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) content(path []string, parent any, c *openapi3.Content) (ok bool, err error) {
+func (wn nodeWalker) content(path []string, c *openapi3.Content) (ok bool, err error) {
 	if c == nil || len(*c) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, c); !ok || err != nil {
-		return
-	}
-	for n, mt := range *c {
-		if ok, err = wn.mediaType(append(path, n), parent, mt); !ok || err != nil {
-			return
+	return wn.node(path, c, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *c) {
+			mt := (*c)[n]
+			if ok, err = wn.mediaType(append(path, n), mt); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) mediaType(path []string, parent any, mt *openapi3.MediaType) (ok bool, err error) {
+func (wn nodeWalker) mediaType(path []string, mt *openapi3.MediaType) (ok bool, err error) {
 	if mt == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, mt); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.schemaRef(append(path, "schema"), parent, mt.Schema); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.examples(append(path, "examples"), parent, &mt.Examples); !ok || err != nil {
-		return
-	}
-	for n, enc := range mt.Encoding {
-		if ok, err = wn.encoding(append(path, n), parent, enc); !ok || err != nil {
+	return wn.node(path, mt, func() (ok bool, err error) {
+		if ok, err = wn.schemaRef(append(path, "schema"), mt.Schema); !ok || err != nil {
 			return
 		}
-	}
-	return true, nil
+		if ok, err = wn.examples(append(path, "examples"), &mt.Examples); !ok || err != nil {
+			return
+		}
+		epath := append(path, "encoding")
+		for _, n := range orderedKeys(&wn.opts, epath, mt.Encoding) {
+			if ok, err = wn.encoding(append(epath, n), mt.Encoding[n]); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) encoding(path []string, parent any, mt *openapi3.Encoding) (ok bool, err error) {
+func (wn nodeWalker) encoding(path []string, mt *openapi3.Encoding) (ok bool, err error) {
 	if mt == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, mt); !ok || err != nil {
-		return
-	}
-	return wn.headers(path, parent, &mt.Headers)
+	return wn.node(path, mt, func() (ok bool, err error) {
+		return wn.headers(append(path, "headers"), &mt.Headers)
+	})
 }
 
-func (wn nodeWalker) headers(path []string, parent any, hdrs *openapi3.Headers) (ok bool, err error) {
+func (wn nodeWalker) headers(path []string, hdrs *openapi3.Headers) (ok bool, err error) {
 	if hdrs == nil || len(*hdrs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, hdrs); !ok || err != nil {
-		return
-	}
-	for n, hdr := range *hdrs {
-		if ok, err = wn.headerRef(append(path, n), parent, hdr); !ok || err != nil {
-			return
+	return wn.node(path, hdrs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *hdrs) {
+			hdr := (*hdrs)[n]
+			if ok, err = wn.headerRef(append(path, n), hdr); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) headerRef(path []string, parent any, hdr *openapi3.HeaderRef) (ok bool, err error) {
+func (wn nodeWalker) headerRef(path []string, hdr *openapi3.HeaderRef) (ok bool, err error) {
 	if hdr == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, hdr); !ok || err != nil {
-		return
-	}
-	return wn.parameter(path, parent, &hdr.Value.Parameter)
+	return wn.node(path, hdr, func() (ok bool, err error) {
+		return wn.parameter(path, &hdr.Value.Parameter)
+	})
 }
 
-func (wn nodeWalker) components(path []string, parent any, c *openapi3.Components) (ok bool, err error) {
+func (wn nodeWalker) components(path []string, c *openapi3.Components) (ok bool, err error) {
 	if c == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, c); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.schemas(append(path, "schemas"), parent, &c.Schemas); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.parametersMap(append(path, "parameters"), parent, &c.Parameters); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.headers(append(path, "headers"), parent, &c.Headers); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.responses(append(path, "responses"), parent, &c.Responses); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.links(append(path, "links"), parent, &c.Links); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.examples(append(path, "examples"), parent, &c.Examples); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.callbacks(append(path, "callbacks"), parent, &c.Callbacks); !ok || err != nil {
-		return
-	}
-	ok, err = wn.securitySchemes(append(path, "securitySchemes"), parent, &c.SecuritySchemes)
-	return true, nil
+	return wn.node(path, c, func() (ok bool, err error) {
+		if ok, err = wn.schemas(append(path, "schemas"), &c.Schemas); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.parametersMap(append(path, "parameters"), &c.Parameters); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.headers(append(path, "headers"), &c.Headers); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.requestBodies(append(path, "requestBodies"), &c.RequestBodies); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.responses(append(path, "responses"), &c.Responses); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.links(append(path, "links"), &c.Links); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.examples(append(path, "examples"), &c.Examples); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.callbacks(append(path, "callbacks"), &c.Callbacks); !ok || err != nil {
+			return
+		}
+		return wn.securitySchemes(append(path, "securitySchemes"), &c.SecuritySchemes)
+	})
 }
 
-func (wn nodeWalker) securitySchemes(path []string, parent any, scs *openapi3.SecuritySchemes) (ok bool, err error) {
+func (wn nodeWalker) securitySchemes(path []string, scs *openapi3.SecuritySchemes) (ok bool, err error) {
 	if scs == nil || len(*scs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, scs); !ok || err != nil {
-		return
-	}
-	for n, sc := range *scs {
-		if ok, err = wn.securitySchemeRef(append(path, n), parent, sc); !ok || err != nil {
-			return
+	return wn.node(path, scs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *scs) {
+			sc := (*scs)[n]
+			if ok, err = wn.securitySchemeRef(append(path, n), sc); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) securitySchemeRef(path []string, parent any, sr *openapi3.SecuritySchemeRef) (ok bool, err error) {
+func (wn nodeWalker) securitySchemeRef(path []string, sr *openapi3.SecuritySchemeRef) (ok bool, err error) {
 	if sr == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, sr); !ok || err != nil {
-		return
-	}
-	ok, err = wn.securityScheme(path, parent, sr.Value)
-	return true, nil
+	return wn.node(path, sr, func() (ok bool, err error) {
+		return wn.securityScheme(path, sr.Value)
+	})
 }
 
-func (wn nodeWalker) securityScheme(path []string, parent any, sr *openapi3.SecurityScheme) (ok bool, err error) {
+func (wn nodeWalker) securityScheme(path []string, sr *openapi3.SecurityScheme) (ok bool, err error) {
 	if sr == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, sr); !ok || err != nil {
-		return
-	}
-	ok, err = wn.oauthFlows(append(path, "flows"), parent, sr.Flows)
-	return true, nil
+	return wn.node(path, sr, func() (ok bool, err error) {
+		return wn.oauthFlows(append(path, "flows"), sr.Flows)
+	})
 }
 
-func (wn nodeWalker) oauthFlows(path []string, parent any, flws *openapi3.OAuthFlows) (ok bool, err error) {
+func (wn nodeWalker) oauthFlows(path []string, flws *openapi3.OAuthFlows) (ok bool, err error) {
 	if flws == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, flws); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.oauthFlow(append(path, "implicit"), parent, flws.Implicit); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.oauthFlow(append(path, "password"), parent, flws.Password); !ok || err != nil {
-		return
-	}
-	if ok, err = wn.oauthFlow(append(path, "clientCredentials"), parent, flws.ClientCredentials); !ok || err != nil {
-		return
-	}
-	ok, err = wn.oauthFlow(append(path, "authorizationCode"), parent, flws.AuthorizationCode)
-	return true, nil
+	return wn.node(path, flws, func() (ok bool, err error) {
+		if ok, err = wn.oauthFlow(append(path, "implicit"), flws.Implicit); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.oauthFlow(append(path, "password"), flws.Password); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.oauthFlow(append(path, "clientCredentials"), flws.ClientCredentials); !ok || err != nil {
+			return
+		}
+		return wn.oauthFlow(append(path, "authorizationCode"), flws.AuthorizationCode)
+	})
 }
 
-func (wn nodeWalker) oauthFlow(path []string, parent any, flw *openapi3.OAuthFlow) (ok bool, err error) {
+func (wn nodeWalker) oauthFlow(path []string, flw *openapi3.OAuthFlow) (ok bool, err error) {
 	if flw == nil {
 		return true, nil
 	}
-	return wn.visit(path, parent, flw)
+	return wn.visitLeaf(path, flw)
 }
 
-func (wn nodeWalker) requestBodies(path []string, parent any, rbs *openapi3.RequestBodies) (ok bool, err error) {
+func (wn nodeWalker) requestBodies(path []string, rbs *openapi3.RequestBodies) (ok bool, err error) {
 	if rbs == nil || len(*rbs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, &rbs); !ok || err != nil {
-		return
-	}
-	for n, rb := range *rbs {
-		if ok, err = wn.requestBodyRef(append(path, n), parent, rb); !ok || err != nil {
-			return
+	return wn.node(path, rbs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *rbs) {
+			rb := (*rbs)[n]
+			if ok, err = wn.requestBodyRef(append(path, n), rb); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) requestBodyRef(path []string, parent any, req *openapi3.RequestBodyRef) (ok bool, err error) {
+func (wn nodeWalker) requestBodyRef(path []string, req *openapi3.RequestBodyRef) (ok bool, err error) {
 	if req == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, req); !ok || err != nil {
-		return
-	}
-	return wn.content(append(path, "content"), parent, &req.Value.Content)
+	return wn.node(path, req, func() (ok bool, err error) {
+		return wn.content(append(path, "content"), &req.Value.Content)
+	})
 }
 
-func (wn nodeWalker) schemas(path []string, parent any, schemas *openapi3.Schemas) (ok bool, err error) {
+func (wn nodeWalker) schemas(path []string, schemas *openapi3.Schemas) (ok bool, err error) {
 	if schemas == nil || len(*schemas) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, schemas); !ok || err != nil {
-		return
-	}
-	for name, schema := range *schemas {
-		if ok, err = wn.schemaRef(append(path, name), parent, schema); !ok || err != nil {
-			return
-		}
-	}
-	return true, nil
+	return wn.node(path, schemas, func() (ok bool, err error) {
+		return wn.forEach(path, orderedKeys(&wn.opts, path, *schemas), func(wn nodeWalker, path []string, name string) (bool, error) {
+			return wn.schemaRef(path, (*schemas)[name])
+		})
+	})
 }
 
-func (wn nodeWalker) schemaRefs(path []string, parent any, srefs *openapi3.SchemaRefs) (ok bool, err error) {
+func (wn nodeWalker) schemaRefs(path []string, srefs *openapi3.SchemaRefs) (ok bool, err error) {
 	if srefs == nil || len(*srefs) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, srefs); !ok || err != nil {
-		return
-	}
-	for i, sref := range *srefs {
-		if ok, err = wn.schemaRef(append(path, strconv.Itoa(i)), parent, sref); !ok || err != nil {
-			return
+	return wn.node(path, srefs, func() (ok bool, err error) {
+		for i, sref := range *srefs {
+			if ok, err = wn.schemaRef(append(path, strconv.Itoa(i)), sref); !ok || err != nil {
+				return
+			}
 		}
-	}
-	return true, nil
+		return true, nil
+	})
 }
 
-func (wn nodeWalker) schemaRef(path []string, parent any, sref *openapi3.SchemaRef) (ok bool, err error) {
+func (wn nodeWalker) schemaRef(path []string, sref *openapi3.SchemaRef) (ok bool, err error) {
 	if sref == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, sref); !ok || err != nil {
-		return
+	if (!wn.opts.followRefs && len(sref.Ref) > 0) || sref.Value == nil {
+		// Unresolved $refs have no value.
+		return wn.visitLeaf(path, sref)
+	}
+	if wn.state.schemas[sref.Value] {
+		parent := wn.state.parent()
+		return wn.node(path, sref, func() (ok bool, err error) {
+			_, ok, err = wn.call(wn.cycleVisitor, path, parent, sref)
+			return
+		})
 	}
-	if !wn.opts.followRefs && len(sref.Ref) > 0 {
+	wn.state.schemas[sref.Value] = true
+	defer delete(wn.state.schemas, sref.Value)
+	return wn.node(path, sref, func() (ok bool, err error) {
+		return wn.schema(path, sref, sref.Value)
+	})
+}
+
+// schema visits the children of a schema.
+func (wn nodeWalker) schema(path []string, sref *openapi3.SchemaRef, s *openapi3.Schema) (ok bool, err error) {
+	if ok, err = wn.schemaRefs(append(path, "oneOf"), &s.OneOf); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.schemaRefs(append(path, "oneOf"), sref, &sref.Value.OneOf); !ok || err != nil {
+	if ok, err = wn.schemaRefs(append(path, "anyOf"), &s.AnyOf); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.schemaRefs(append(path, "anyOf"), sref, &sref.Value.AnyOf); !ok || err != nil {
+	if ok, err = wn.schemaRefs(append(path, "allOf"), &s.AllOf); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.schemaRefs(append(path, "allOf"), sref, &sref.Value.AllOf); !ok || err != nil {
+	if ok, err = wn.schemaRef(append(path, "not"), s.Not); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.schemaRef(append(path, "not"), sref, sref.Value.Not); !ok || err != nil {
+	if ok, err = wn.schemas(append(path, "properties"), &s.Properties); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.schemas(append(path, "properties"), sref, &sref.Value.Properties); !ok || err != nil {
+	if ok, err = wn.schemaRef(append(path, "items"), s.Items); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.schemaRef(append(path, "items"), sref, sref.Value.Items); !ok || err != nil {
+	if ok, err = wn.additionalProperties(append(path, "additionalProperties"), s.AdditionalProperties); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.additionalProperties(append(path, "additionalProperties"), sref, sref.Value.AdditionalProperties); !ok || err != nil {
+	if ok, err = wn.schema31(path, sref, s); !ok || err != nil {
 		return
 	}
-	if ok, err = wn.extensions(append(path, "extensions"), sref, &sref.Value.Extensions); !ok || err != nil {
+	if ok, err = wn.extensions(path, &s.Extensions); !ok || err != nil {
 		return
 	}
-	return wn.discriminator(append(path, "discriminator"), sref, sref.Value.Discriminator)
+	return wn.discriminator(append(path, "discriminator"), s.Discriminator)
 }
 
-func (wn nodeWalker) additionalProperties(path []string, parent any, props openapi3.AdditionalProperties) (ok bool, err error) {
+func (wn nodeWalker) additionalProperties(path []string, props openapi3.AdditionalProperties) (ok bool, err error) {
 	if props.Has == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, props); !ok || err != nil {
-		return
+	return wn.node(path, props, func() (ok bool, err error) {
+		return wn.schemaRef(path, props.Schema)
+	})
+}
+
+// extensions visits the extensions of the node at path.
+func (wn nodeWalker) extensions(path []string, exts *map[string]interface{}) (ok bool, err error) {
+	if wn.state.decoded == nil {
+		return wn.extensionsExcept(path, exts, nil)
 	}
-	return wn.schemaRef(path, props, props.Schema)
+	return wn.extensionsExcept(path, exts, schema31Keywords)
 }
 
-func (wn nodeWalker) extensions(path []string, parent any, exts *map[string]interface{}) (ok bool, err error) {
-	if exts == nil || len(*exts) == 0 {
+// extensionsExcept visits the extensions, of the node at path, that are
+// not in skip. They are visited under "extensions" unless paths are
+// reported as JSON Pointers, in which case they are visited as fields
+// of the node, as they appear in the document, and the map containing
+// them is not visited.
+func (wn nodeWalker) extensionsExcept(path []string, exts *map[string]interface{}, skip map[string]bool) (ok bool, err error) {
+	if exts == nil {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, exts); !ok || err != nil {
-		return
+	if !wn.opts.pointers {
+		path = append(path, "extensions")
 	}
-	for n, ext := range *exts {
-		if ok, err = wn.visit(append(path, n), parent, ext); !ok || err != nil {
-			return
+	keys := make([]string, 0, len(*exts))
+	for _, n := range orderedKeys(&wn.opts, path, *exts) {
+		if !skip[n] {
+			keys = append(keys, n)
 		}
 	}
-	return true, nil
-}
-
-func (wn nodeWalker) discriminator(path []string, parent any, disc *openapi3.Discriminator) (ok bool, err error) {
-	if disc == nil {
+	if len(keys) == 0 {
 		return true, nil
 	}
-	if ok, err = wn.visit(path, parent, disc); !ok || err != nil {
-		return
+	visit := func() (ok bool, err error) {
+		for _, n := range keys {
+			ext := (*exts)[n]
+			if ok, err = wn.visitLeaf(append(path, n), ext); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
 	}
-	if ok, err = wn.visit(append(path, "mapping"), parent, disc.Mapping); !ok || err != nil {
-		return
+	if wn.opts.pointers {
+		return visit()
 	}
-	if ok, err = wn.extensions(path, parent, &disc.Extensions); !ok || err != nil {
-		return
+	return wn.node(path, exts, visit)
+}
+
+func (wn nodeWalker) discriminator(path []string, disc *openapi3.Discriminator) (ok bool, err error) {
+	if disc == nil {
+		return true, nil
 	}
-	for name, mapping := range disc.Mapping {
-		if ok, err = wn.visit(append(path, "mapping", name), parent, mapping); !ok || err != nil {
+	return wn.node(path, disc, func() (ok bool, err error) {
+		if ok, err = wn.visitLeaf(append(path, "mapping"), disc.Mapping); !ok || err != nil {
 			return
 		}
-	}
-	return true, nil
+		if ok, err = wn.extensions(path, &disc.Extensions); !ok || err != nil {
+			return
+		}
+		mpath := append(path, "mapping")
+		for _, name := range orderedKeys(&wn.opts, mpath, disc.Mapping) {
+			if ok, err = wn.visitLeaf(append(mpath, name), disc.Mapping[name]); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
 }
@@ -0,0 +1,266 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// V2Walker represents the interface implemented by walkers for
+// swagger 2.0 documents.
+type V2Walker interface {
+	// Walk is equivalent to WalkContext(context.Background(), doc).
+	Walk(doc *openapi2.T) error
+	// WalkContext walks the supplied document, see Walker.WalkContext.
+	WalkContext(ctx context.Context, doc *openapi2.T) error
+}
+
+// NewV2Walker returns a walker that will visit every node in a swagger 2.0
+// document. It follows the same conventions and accepts the same options
+// as NewWalker. Note that the $refs in swagger 2.0 documents are not
+// resolved when they are parsed and hence WalkerFollowRefs has no effect
+// unless the document has been resolved by other means.
+func NewV2Walker(v Visitor, opts ...WalkerOption) V2Walker {
+	return NewV2ContextWalker(v.withContext(), opts...)
+}
+
+// NewV2ContextWalker is like NewV2Walker except that the Visitor is also
+// passed the context supplied to WalkContext.
+func NewV2ContextWalker(v ContextVisitor, opts ...WalkerOption) V2Walker {
+	return v2Walker{nodeWalker: *NewContextWalker(v, opts...).(*nodeWalker)}
+}
+
+type v2Walker struct {
+	nodeWalker
+}
+
+// Walk implements V2Walker.
+func (wn v2Walker) Walk(doc *openapi2.T) error {
+	return wn.WalkContext(context.Background(), doc)
+}
+
+// WalkContext implements V2Walker.
+func (wn v2Walker) WalkContext(ctx context.Context, doc *openapi2.T) error {
+	if wn.opts.err != nil {
+		return wn.opts.err
+	}
+	wn.state = newWalkState(ctx, doc, &wn.opts)
+	if wn.opts.allFields {
+		_, err := wn.reflectChildren(nil, reflect.ValueOf(doc).Elem())
+		return err
+	}
+	_, err := wn.doc(doc)
+	return err
+}
+
+func (wn v2Walker) doc(doc *openapi2.T) (ok bool, err error) {
+	if ok, err = wn.visitLeaf([]string{"info"}, &doc.Info); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.schemas([]string{"definitions"}, (*openapi3.Schemas)(&doc.Definitions)); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.parametersMap([]string{"parameters"}, &doc.Parameters); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.responses([]string{"responses"}, &doc.Responses); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.securitySchemes([]string{"securityDefinitions"}, &doc.SecurityDefinitions); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.paths([]string{"paths"}, &doc.Paths); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.securityReqs([]string{"security"}, &doc.Security); !ok || err != nil {
+		return
+	}
+	if ok, err = wn.externalDocs([]string{"externalDocs"}, doc.ExternalDocs); !ok || err != nil {
+		return
+	}
+	return wn.tags([]string{"tags"}, &doc.Tags)
+}
+
+func (wn v2Walker) securityReqs(path []string, reqs *openapi2.SecurityRequirements) (ok bool, err error) {
+	if reqs == nil || len(*reqs) == 0 {
+		return true, nil
+	}
+	return wn.node(path, reqs, func() (ok bool, err error) {
+		for i, req := range *reqs {
+			if ok, err = wn.visitLeaf(append(path, strconv.Itoa(i)), req); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
+}
+
+func (wn v2Walker) securitySchemes(path []string, scs *map[string]*openapi2.SecurityScheme) (ok bool, err error) {
+	if scs == nil || len(*scs) == 0 {
+		return true, nil
+	}
+	return wn.node(path, scs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *scs) {
+			if sc := (*scs)[n]; sc != nil {
+				if ok, err = wn.visitLeaf(append(path, n), sc); !ok || err != nil {
+					return
+				}
+			}
+		}
+		return true, nil
+	})
+}
+
+func (wn v2Walker) paths(path []string, paths *map[string]*openapi2.PathItem) (ok bool, err error) {
+	if paths == nil || len(*paths) == 0 {
+		return true, nil
+	}
+	return wn.node(path, paths, func() (ok bool, err error) {
+		return wn.forEach(path, orderedKeys(&wn.opts, path, *paths), func(nw nodeWalker, path []string, p string) (bool, error) {
+			return v2Walker{nw}.pathItem(path, (*paths)[p])
+		})
+	})
+}
+
+func (wn v2Walker) pathItem(path []string, pi *openapi2.PathItem) (ok bool, err error) {
+	if pi == nil {
+		return true, nil
+	}
+	return wn.node(path, pi, func() (ok bool, err error) {
+		for _, ops := range []struct {
+			name string
+			op   *openapi2.Operation
+		}{
+			{"delete", pi.Delete},
+			{"get", pi.Get},
+			{"head", pi.Head},
+			{"options", pi.Options},
+			{"patch", pi.Patch},
+			{"post", pi.Post},
+			{"put", pi.Put},
+		} {
+			if ok, err = wn.operation(append(path, ops.name), ops.op); !ok || err != nil {
+				return
+			}
+		}
+		return wn.parameters(append(path, "parameters"), &pi.Parameters)
+	})
+}
+
+func (wn v2Walker) operation(path []string, op *openapi2.Operation) (ok bool, err error) {
+	if op == nil {
+		return true, nil
+	}
+	return wn.node(path, op, func() (ok bool, err error) {
+		if ok, err = wn.parameters(append(path, "parameters"), &op.Parameters); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.responses(append(path, "responses"), &op.Responses); !ok || err != nil {
+			return
+		}
+		if ok, err = wn.securityReqs(append(path, "security"), op.Security); !ok || err != nil {
+			return
+		}
+		return wn.externalDocs(append(path, "externalDocs"), op.ExternalDocs)
+	})
+}
+
+func (wn v2Walker) parametersMap(path []string, pars *map[string]*openapi2.Parameter) (ok bool, err error) {
+	if pars == nil || len(*pars) == 0 {
+		return true, nil
+	}
+	return wn.node(path, pars, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *pars) {
+			par := (*pars)[n]
+			if ok, err = wn.parameter(append(path, n), par); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
+}
+
+// parameters visits a list of parameters. As for openapi3 documents,
+// parameters are identified as per paramName.
+func (wn v2Walker) parameters(path []string, pars *openapi2.Parameters) (ok bool, err error) {
+	if pars == nil || len(*pars) == 0 {
+		return true, nil
+	}
+	return wn.node(path, pars, func() (ok bool, err error) {
+		for i, par := range *pars {
+			if par == nil {
+				continue
+			}
+			if ok, err = wn.parameter(append(path, wn.paramName(i, par.Name)), par); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
+}
+
+func (wn v2Walker) parameter(path []string, par *openapi2.Parameter) (ok bool, err error) {
+	if par == nil {
+		return true, nil
+	}
+	return wn.node(path, par, func() (ok bool, err error) {
+		if ok, err = wn.schemaRef(append(path, "schema"), par.Schema); !ok || err != nil {
+			return
+		}
+		return wn.schemaRef(append(path, "items"), par.Items)
+	})
+}
+
+func (wn v2Walker) responses(path []string, resps *map[string]*openapi2.Response) (ok bool, err error) {
+	if resps == nil || len(*resps) == 0 {
+		return true, nil
+	}
+	return wn.node(path, resps, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *resps) {
+			r := (*resps)[n]
+			if ok, err = wn.response(append(path, n), r); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
+}
+
+func (wn v2Walker) response(path []string, resp *openapi2.Response) (ok bool, err error) {
+	if resp == nil {
+		return true, nil
+	}
+	return wn.node(path, resp, func() (ok bool, err error) {
+		if ok, err = wn.schemaRef(append(path, "schema"), resp.Schema); !ok || err != nil {
+			return
+		}
+		return wn.headers(append(path, "headers"), &resp.Headers)
+	})
+}
+
+func (wn v2Walker) headers(path []string, hdrs *map[string]*openapi2.Header) (ok bool, err error) {
+	if hdrs == nil || len(*hdrs) == 0 {
+		return true, nil
+	}
+	return wn.node(path, hdrs, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *hdrs) {
+			hdr := (*hdrs)[n]
+			if hdr == nil {
+				continue
+			}
+			if ok, err = wn.node(append(path, n), hdr, func() (ok bool, err error) {
+				return wn.schemaRef(append(path, n, "items"), hdr.Items)
+			}); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/openapi"
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestWalkV2(t *testing.T) {
+	doc := loadV2("v2kitchensink.json")
+	v := &testVisitor{}
+	wk := openapi.NewV2Walker(v.visitor, openapi.WalkerOrder(openapi.SortedOrder))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.paths, strings.Split(strings.TrimSpace(`
+info
+definitions
+definitions:Pet
+definitions:Pet:properties
+definitions:Pet:properties:name
+definitions:Pet:properties:tags
+definitions:Pet:properties:tags:items
+parameters
+parameters:Limit
+responses
+responses:Error
+responses:Error:schema
+securityDefinitions
+securityDefinitions:apiKey
+securityDefinitions:oauth
+paths
+paths:/pets/{id}
+paths:/pets/{id}:get
+paths:/pets/{id}:get:parameters
+paths:/pets/{id}:get:parameters:0
+paths:/pets/{id}:get:parameters:tags
+paths:/pets/{id}:get:parameters:tags:items
+paths:/pets/{id}:get:responses
+paths:/pets/{id}:get:responses:200
+paths:/pets/{id}:get:responses:200:schema
+paths:/pets/{id}:get:responses:200:headers
+paths:/pets/{id}:get:responses:200:headers:X-Rate-Limit
+paths:/pets/{id}:get:responses:default
+paths:/pets/{id}:get:security
+paths:/pets/{id}:get:security:0
+paths:/pets/{id}:put
+paths:/pets/{id}:put:parameters
+paths:/pets/{id}:put:parameters:body
+paths:/pets/{id}:put:parameters:body:schema
+paths:/pets/{id}:put:responses
+paths:/pets/{id}:put:responses:204
+paths:/pets/{id}:parameters
+paths:/pets/{id}:parameters:id
+security
+security:0
+externalDocs
+tags
+tags:0`), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// JSON Schema 2020-12 keywords are visited as extensions in swagger
+	// 2.0 documents and are not decoded.
+	doc = loadV2("v2kitchensink.json")
+	pet := doc.Definitions["Pet"].Value
+	pet.Extensions = map[string]any{"if": map[string]any{"required": []any{"name"}}}
+	for _, all := range []bool{false, true} {
+		v := &testVisitor{}
+		wk := openapi.NewV2Walker(v.visitor, openapi.WalkerAllFields(all), openapi.WalkerVisitPrefix("definitions", "Pet"))
+		if err := wk.Walk(doc); err != nil {
+			t.Fatal(err)
+		}
+		visited := strings.Join(v.paths, "\n")
+		if !strings.Contains(visited, "definitions:Pet:extensions:if") || strings.Contains(visited, "definitions:Pet:if") {
+			t.Errorf("all fields %v: got %v", all, v.paths)
+		}
+		if _, ok := pet.Extensions["if"].(map[string]any); !ok {
+			t.Errorf("all fields %v: got %T", all, pet.Extensions["if"])
+		}
+	}
+
+	doc = loadV2("v2swagger.json")
+	var schemas, ops []string
+	wk = openapi.NewV2Walker(func(path []string, parent, node any) (bool, error) {
+		switch node.(type) {
+		case *openapi3.SchemaRef:
+			if len(path) == 2 {
+				schemas = append(schemas, path[1])
+			}
+		case *openapi2.Operation:
+			ops = append(ops, node.(*openapi2.Operation).OperationID)
+		}
+		return true, nil
+	}, openapi.WalkerOrder(openapi.SortedOrder), openapi.WalkerFollowRefs(true))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := schemas, []string{"ApiResponse", "Category", "Order", "Pet", "Tag", "User"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(ops), 20; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	errFail := errors.New("fail")
+	err := openapi.NewV2Walker(func(path []string, parent, node any) (bool, error) {
+		if _, ok := node.(*openapi2.Response); ok {
+			return false, errFail
+		}
+		return true, nil
+	}, openapi.WalkerOrder(openapi.SortedOrder), openapi.WalkerJSONPointers(true)).Walk(loadV2("v2kitchensink.json"))
+	if err == nil || err.Error() != "/responses/Error: fail" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
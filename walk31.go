@@ -0,0 +1,330 @@
+// Copyright 2022 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI 3.1 constructs are not supported by kin-openapi's openapi3
+// package and are instead stored, as generic maps and slices, in the
+// Extensions of the enclosing node. The walker decodes these into their
+// typed equivalents, eg. *openapi3.PathItem and *openapi3.SchemaRef,
+// before visiting them. Walkers never modify the document, the decoded
+// values are owned by the walk and shared by all of its workers so that
+// a node that is reached more than once, eg. via $refs, is decoded only
+// once. Apply, on the other hand, replaces the generic values in the
+// document with the decoded ones so that changes made to them, directly
+// or via a Cursor, are retained. Decode31 may be used to do the same for
+// walkers whose visitors modify the nodes they are passed. Note that any
+// $refs within them are not resolved by the loader and that they are not
+// decoded when walking swagger 2.0 documents.
+//
+// JSON Schema 2020-12 type arrays, eg. type: [string, "null"], are
+// rejected by kin-openapi's loader and must be rewritten, using
+// RewriteTypeArrays, before the document is loaded.
+
+// schema31Keywords are the JSON Schema 2020-12 keywords visited by the
+// walker, they are not visited as extensions.
+var schema31Keywords = map[string]bool{
+	"$defs":            true,
+	"const":            true,
+	"dependentSchemas": true,
+	"else":             true,
+	"if":               true,
+	"prefixItems":      true,
+	"then":             true,
+}
+
+// doc31Keywords are the OpenAPI 3.1 fields of a document that are
+// visited by the walker rather than as extensions.
+var doc31Keywords = map[string]bool{
+	"webhooks": true,
+}
+
+// Decode31 replaces the OpenAPI 3.1 constructs stored in the Extensions
+// of doc with their typed equivalents, as per Apply, so that changes
+// made to them by the visitors of subsequent walks are retained. It
+// must be called before any such walk since walkers never modify the
+// document themselves.
+func Decode31(ctx context.Context, doc *openapi3.T) error {
+	return Apply(ctx, doc, func(*Cursor) (bool, error) { return true, nil }, nil)
+}
+
+// decoded31 records the OpenAPI 3.1 constructs decoded during a walk.
+type decoded31 struct {
+	// store is set by Apply, in which case decoded values are stored
+	// in the document's Extensions rather than in values.
+	store  bool
+	mu     sync.Mutex
+	values map[decodedKey]any
+}
+
+// decodedKey identifies an extension of a node, eg. the *openapi3.Schema
+// whose Extensions contain it.
+type decodedKey struct {
+	node any
+	key  string
+}
+
+func newDecoded31(store bool) *decoded31 {
+	return &decoded31{store: store, values: map[decodedKey]any{}}
+}
+
+// extensionAs returns the extension key of node, whose extensions are
+// exts, decoded as a T, or nil if there is no such extension or if d is
+// nil, ie. the document is not an OpenAPI 3 document.
+func extensionAs[T any](d *decoded31, node any, exts map[string]any, key string) (*T, error) {
+	if d == nil {
+		return nil, nil
+	}
+	v, ok := exts[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	if t, ok := v.(*T); ok {
+		return t, nil
+	}
+	if d.store {
+		t, err := decodeAs[T](v)
+		if err == nil {
+			exts[key] = t
+		}
+		return t, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dk := decodedKey{node: node, key: key}
+	if t, ok := d.values[dk].(*T); ok {
+		return t, nil
+	}
+	t, err := decodeAs[T](v)
+	if err == nil {
+		d.values[dk] = t
+	}
+	return t, err
+}
+
+func decodeAs[T any](v any) (*T, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	t := new(T)
+	if err := json.Unmarshal(buf, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (wn nodeWalker) extensionError(path []string, err error) (bool, error) {
+	return false, wn.pathError(path, nil, err)
+}
+
+func (wn nodeWalker) webhooks(path []string, doc *openapi3.T) (ok bool, err error) {
+	hooks, err := extensionAs[map[string]*openapi3.PathItem](wn.state.decoded, doc, doc.Extensions, "webhooks")
+	if err != nil {
+		return wn.extensionError(path, err)
+	}
+	if hooks == nil || len(*hooks) == 0 {
+		return true, nil
+	}
+	return wn.node(path, hooks, func() (ok bool, err error) {
+		for _, n := range orderedKeys(&wn.opts, path, *hooks) {
+			if ok, err = wn.pathItem(append(path, n), (*hooks)[n]); !ok || err != nil {
+				return
+			}
+		}
+		return true, nil
+	})
+}
+
+// schema31 visits the JSON Schema 2020-12 keywords of a schema.
+func (wn nodeWalker) schema31(path []string, sref *openapi3.SchemaRef, s *openapi3.Schema) (ok bool, err error) {
+	d := wn.state.decoded
+	if d == nil {
+		return true, nil
+	}
+	for _, kw := range []string{"$defs", "dependentSchemas"} {
+		schemas, err := extensionAs[openapi3.Schemas](d, s, s.Extensions, kw)
+		if err != nil {
+			return wn.extensionError(append(path, kw), err)
+		}
+		if ok, err = wn.schemas(append(path, kw), schemas); !ok || err != nil {
+			return ok, err
+		}
+	}
+	items, err := extensionAs[openapi3.SchemaRefs](d, s, s.Extensions, "prefixItems")
+	if err != nil {
+		return wn.extensionError(append(path, "prefixItems"), err)
+	}
+	if ok, err = wn.schemaRefs(append(path, "prefixItems"), items); !ok || err != nil {
+		return
+	}
+	for _, kw := range []string{"if", "then", "else"} {
+		cond, err := extensionAs[openapi3.SchemaRef](d, s, s.Extensions, kw)
+		if err != nil {
+			return wn.extensionError(append(path, kw), err)
+		}
+		if ok, err = wn.schemaRef(append(path, kw), cond); !ok || err != nil {
+			return ok, err
+		}
+	}
+	if v, ok := s.Extensions["const"]; ok {
+		return wn.visitLeaf(append(path, "const"), v)
+	}
+	return true, nil
+}
+
+// RewriteTypeArrays returns data, an OpenAPI 3.1 document in YAML or
+// JSON, rewritten as YAML with the JSON Schema 2020-12 type arrays that
+// it contains replaced by their OpenAPI 3.0 equivalents so that it can
+// be loaded by kin-openapi's loader and walked. A "null" type is
+// replaced by nullable: true, a single remaining type by that type and
+// multiple types by an anyOf, or if the schema already has an anyOf, an
+// allOf entry, with a schema for each type. For example,
+// type: [string, "null"] becomes type: string, nullable: true.
+// Examples, default, enum and const values and extensions are left
+// unchanged.
+func RewriteTypeArrays(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	rewriteTypeArrays(&doc, false)
+	out := &bytes.Buffer{}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// valueKeywords are the keywords whose values are data rather than
+// schemas and hence are not searched for type arrays.
+var valueKeywords = map[string]bool{
+	"const":    true,
+	"default":  true,
+	"enum":     true,
+	"example":  true,
+	"examples": true,
+}
+
+// nameMaps are the keywords whose values map names, eg. of properties or
+// responses, to schemas or the objects that contain them, rather than
+// keywords to values. Their keys may be the same as any keyword, eg. a
+// property may be called default.
+var nameMaps = map[string]bool{
+	"$defs":             true,
+	"callbacks":         true,
+	"content":           true,
+	"definitions":       true,
+	"dependentSchemas":  true,
+	"encoding":          true,
+	"headers":           true,
+	"links":             true,
+	"parameters":        true,
+	"paths":             true,
+	"patternProperties": true,
+	"properties":        true,
+	"requestBodies":     true,
+	"responses":         true,
+	"schemas":           true,
+	"webhooks":          true,
+}
+
+// rewriteTypeArrays rewrites the type arrays within n. If names is set,
+// n is a mapping whose keys are names rather than keywords, see nameMaps.
+func rewriteTypeArrays(n *yaml.Node, names bool) {
+	if n.Kind != yaml.MappingNode {
+		for _, c := range n.Content {
+			rewriteTypeArrays(c, false)
+		}
+		return
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		key := n.Content[i].Value
+		if names {
+			rewriteTypeArrays(n.Content[i+1], false)
+			continue
+		}
+		if valueKeywords[key] || strings.HasPrefix(key, "x-") {
+			continue
+		}
+		rewriteTypeArrays(n.Content[i+1], nameMaps[key])
+	}
+	if names {
+		return
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if k, v := n.Content[i], n.Content[i+1]; k.Value == "type" && v.Kind == yaml.SequenceNode {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			rewriteTypeArray(n, v)
+			return
+		}
+	}
+}
+
+// rewriteTypeArray adds the OpenAPI 3.0 equivalent of the type array
+// types to the schema n.
+func rewriteTypeArray(n, types *yaml.Node) {
+	var names []string
+	nullable := false
+	for _, t := range types.Content {
+		if t.Value == "null" {
+			nullable = true
+			continue
+		}
+		names = append(names, t.Value)
+	}
+	str := func(v string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	}
+	schema := func(typ string) *yaml.Node {
+		s := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		s.Content = append(s.Content, str("type"), str(typ))
+		if nullable {
+			s.Content = append(s.Content, str("nullable"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		}
+		return s
+	}
+	switch len(names) {
+	case 0:
+		if nullable {
+			null := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+			n.Content = append(n.Content,
+				str("nullable"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+				str("enum"), &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{null}})
+		}
+	case 1:
+		n.Content = append(n.Content, schema(names[0]).Content...)
+	default:
+		anyOf := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, name := range names {
+			anyOf.Content = append(anyOf.Content, schema(name))
+		}
+		if mappingValue(n, "anyOf") == nil {
+			n.Content = append(n.Content, str("anyOf"), anyOf)
+			return
+		}
+		entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{str("anyOf"), anyOf}}
+		if allOf := mappingValue(n, "allOf"); allOf != nil && allOf.Kind == yaml.SequenceNode {
+			allOf.Content = append(allOf.Content, entry)
+			return
+		}
+		n.Content = append(n.Content, str("allOf"), &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{entry}})
+	}
+}
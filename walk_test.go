@@ -5,9 +5,13 @@
 package openapi_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"testing"
@@ -68,3 +72,747 @@ func TestWalk(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestWalkOrder(t *testing.T) {
+	doc := loadYAML("order.yaml")
+	data, err := os.ReadFile(filepath.Join("testdata", "order.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := openapi.NewSource(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	walk := func(opts ...openapi.WalkerOption) []string {
+		v := &testVisitor{}
+		if err := openapi.NewWalker(v.visitor, opts...).Walk(doc); err != nil {
+			t.Fatal(err)
+		}
+		return v.paths
+	}
+
+	sourceOrder := walk(openapi.WalkerSource(src), openapi.WalkerOrder(openapi.SourceOrder))
+	if got, want := sourceOrder, []string{
+		"info",
+		"components",
+		"components:schemas",
+		"components:schemas:Zebra",
+		"components:schemas:Zebra:properties",
+		"components:schemas:Zebra:properties:stripes",
+		"components:schemas:Zebra:properties:name",
+		"components:schemas:Ant",
+		"components:schemas:Ant:properties",
+		"components:schemas:Ant:properties:legs",
+		"components:schemas:Ant:properties:colony",
+		"paths",
+		"paths:/zebras",
+		"paths:/zebras:get",
+		"paths:/zebras:get:responses",
+		"paths:/zebras:get:responses:200",
+		"paths:/zebras:get:responses:404",
+		"paths:/ants",
+		"paths:/ants:get",
+		"paths:/ants:get:responses",
+		"paths:/ants:get:responses:default",
+		"paths:/ants:get:responses:200",
+		"externalDocs",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	sortedOrder := walk(openapi.WalkerOrder(openapi.SortedOrder))
+	if got, want := sortedOrder, []string{
+		"info",
+		"components",
+		"components:schemas",
+		"components:schemas:Ant",
+		"components:schemas:Ant:properties",
+		"components:schemas:Ant:properties:colony",
+		"components:schemas:Ant:properties:legs",
+		"components:schemas:Zebra",
+		"components:schemas:Zebra:properties",
+		"components:schemas:Zebra:properties:name",
+		"components:schemas:Zebra:properties:stripes",
+		"paths",
+		"paths:/ants",
+		"paths:/ants:get",
+		"paths:/ants:get:responses",
+		"paths:/ants:get:responses:200",
+		"paths:/ants:get:responses:default",
+		"paths:/zebras",
+		"paths:/zebras:get",
+		"paths:/zebras:get:responses",
+		"paths:/zebras:get:responses:200",
+		"paths:/zebras:get:responses:404",
+		"externalDocs",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Source order falls back to sorted order when there is no source.
+	if got, want := walk(openapi.WalkerOrder(openapi.SourceOrder)), sortedOrder; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The same order must be produced for every walk.
+	doc = loadYAML("benchling.yaml")
+	first := walk(openapi.WalkerOrder(openapi.SortedOrder))
+	for i := 0; i < 3; i++ {
+		if got, want := walk(openapi.WalkerOrder(openapi.SortedOrder)), first; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: walk order is not deterministic", i)
+		}
+	}
+}
+
+func TestWalkPostVisit(t *testing.T) {
+	doc := loadYAML("order.yaml")
+	var events []string
+	pre := func(path []string, parent, node any) (bool, error) {
+		events = append(events, "pre:"+strings.Join(path, ":"))
+		return true, nil
+	}
+	post := func(path []string, parent, node any) (bool, error) {
+		events = append(events, "post:"+strings.Join(path, ":"))
+		return true, nil
+	}
+	wk := openapi.NewWalker(pre,
+		openapi.WalkerPostVisit(post),
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerVisitPrefix("components", "schemas", "Ant"))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := events, []string{
+		"pre:components:schemas:Ant",
+		"pre:components:schemas:Ant:properties",
+		"pre:components:schemas:Ant:properties:colony",
+		"post:components:schemas:Ant:properties:colony",
+		"pre:components:schemas:Ant:properties:legs",
+		"post:components:schemas:Ant:properties:legs",
+		"post:components:schemas:Ant:properties",
+		"post:components:schemas:Ant",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Every node must be post-visited, in reverse order of nesting, with
+	// the same arguments as it was pre-visited with.
+	type visit struct {
+		path         string
+		parent, node any
+	}
+	var stack []visit
+	npre, npost := 0, 0
+	pre = func(path []string, parent, node any) (bool, error) {
+		stack = append(stack, visit{strings.Join(path, ":"), parent, node})
+		npre++
+		return true, nil
+	}
+	post = func(path []string, parent, node any) (bool, error) {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if got, want := (visit{strings.Join(path, ":"), parent, node}), top; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got.path, want.path)
+		}
+		npost++
+		return true, nil
+	}
+	if err := openapi.NewWalker(pre, openapi.WalkerPostVisit(post)).Walk(loadYAML("benchling.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := npost, npre; got != want || len(stack) != 0 {
+		t.Errorf("got %v, want %v, %v nodes not post-visited", got, want, len(stack))
+	}
+}
+
+func TestWalkRequestBodiesAndCallbacks(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	visited := map[string]any{}
+	wk := openapi.NewWalker(func(path []string, parent, node any) (bool, error) {
+		visited[strings.Join(path, ":")] = node
+		return true, nil
+	})
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]reflect.Type{
+		"components:requestBodies":                                                              reflect.TypeOf(&openapi3.RequestBodies{}),
+		"components:requestBodies:Pet":                                                          reflect.TypeOf(&openapi3.RequestBodyRef{}),
+		"components:requestBodies:Pet:content:application/json:schema":                          reflect.TypeOf(&openapi3.SchemaRef{}),
+		"components:callbacks:Notify:{$request.body#/url}":                                      reflect.TypeOf(&openapi3.PathItem{}),
+		"components:callbacks:Notify:{$request.body#/url}:post":                                 reflect.TypeOf(&openapi3.Operation{}),
+		"components:callbacks:Notify:{$request.body#/url}:post:responses:200":                   reflect.TypeOf(&openapi3.ResponseRef{}),
+		"paths:/pets/{id}:put:callbacks:onUpdate:{$request.body#/callbackUrl}:post":             reflect.TypeOf(&openapi3.Operation{}),
+		"paths:/pets/{id}:put:callbacks:onUpdate:{$request.body#/callbackUrl}:post:requestBody": reflect.TypeOf(&openapi3.RequestBodyRef{}),
+	} {
+		node, ok := visited[path]
+		if !ok {
+			t.Errorf("%v: not visited", path)
+			continue
+		}
+		if got := reflect.TypeOf(node); got != want {
+			t.Errorf("%v: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestWalkCycles(t *testing.T) {
+	doc := loadYAML("recursive.yaml")
+	cycles := &testVisitor{}
+	nodes := &testVisitor{}
+	wk := openapi.NewWalker(nodes.visitor,
+		openapi.WalkerFollowRefs(true),
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerCycleVisitor(cycles.visitor))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cycles.paths, []string{
+		"components:schemas:Binary:properties:left:oneOf:1",
+		"components:schemas:Binary:properties:right:oneOf:1",
+		"components:schemas:Expr:oneOf:1:properties:left",
+		"components:schemas:Expr:oneOf:1:properties:right",
+		"components:schemas:Tree:properties:children:items",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Recursive schemas are still visited, but their children are not.
+	for _, p := range cycles.paths {
+		found := 0
+		for _, n := range nodes.paths {
+			if strings.HasPrefix(n, p) {
+				found++
+			}
+		}
+		if got, want := found, 1; got != want {
+			t.Errorf("%v: got %v, want %v", p, got, want)
+		}
+	}
+
+	cycles.paths = nil
+	wk = openapi.NewWalker(nodes.visitor, openapi.WalkerCycleVisitor(cycles.visitor))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(cycles.paths), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// walkTree records the structure of a walk so that the expected results
+// of pruning it can be computed.
+type walkTree struct {
+	path     string
+	nodeType string
+	children []*walkTree
+}
+
+func recordWalk(t *testing.T, doc *openapi3.T, opts ...openapi.WalkerOption) *walkTree {
+	root := &walkTree{}
+	stack := []*walkTree{root}
+	pre := func(path []string, parent, node any) (bool, error) {
+		n := &walkTree{path: strings.Join(path, ":"), nodeType: fmt.Sprintf("%T", node)}
+		top := stack[len(stack)-1]
+		top.children = append(top.children, n)
+		stack = append(stack, n)
+		return true, nil
+	}
+	post := func(path []string, parent, node any) (bool, error) {
+		stack = stack[:len(stack)-1]
+		return true, nil
+	}
+	opts = append(opts, openapi.WalkerPostVisit(post))
+	if err := openapi.NewWalker(pre, opts...).Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// pruned returns the paths that will be visited if the children of
+// all nodes of the specified type are skipped.
+func (wt *walkTree) pruned(nodeType string) []string {
+	var paths []string
+	for _, c := range wt.children {
+		paths = append(paths, c.path)
+		if c.nodeType != nodeType {
+			paths = append(paths, c.pruned(nodeType)...)
+		}
+	}
+	return paths
+}
+
+func (wt *walkTree) flatten(fn func(*walkTree) string) []string {
+	var r []string
+	for _, c := range wt.children {
+		r = append(r, fn(c))
+		r = append(r, c.flatten(fn)...)
+	}
+	return r
+}
+
+func (wt *walkTree) types() []string {
+	seen := map[string]bool{}
+	var types []string
+	for _, t := range wt.flatten(func(n *walkTree) string { return n.nodeType }) {
+		if !seen[t] {
+			types = append(types, t)
+		}
+		seen[t] = true
+	}
+	sort.Strings(types)
+	return types
+}
+
+func TestWalkSkip(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	opts := []openapi.WalkerOption{openapi.WalkerOrder(openapi.SortedOrder)}
+	tree := recordWalk(t, doc, opts...)
+	all := tree.pruned("")
+	allTypes := tree.flatten(func(n *walkTree) string { return n.nodeType })
+
+	types := tree.types()
+	if got, want := len(types), 30; got < want {
+		t.Errorf("got %v, want at least %v node types: %v", got, want, types)
+	}
+
+	for _, nodeType := range types {
+		var visited, postVisited []string
+		pre := func(path []string, parent, node any) (bool, error) {
+			visited = append(visited, strings.Join(path, ":"))
+			if fmt.Sprintf("%T", node) == nodeType {
+				return true, openapi.SkipNode
+			}
+			return true, nil
+		}
+		post := func(path []string, parent, node any) (bool, error) {
+			if fmt.Sprintf("%T", node) == nodeType {
+				t.Errorf("%v: %v: post-visitor called for skipped node", nodeType, strings.Join(path, ":"))
+			}
+			postVisited = append(postVisited, strings.Join(path, ":"))
+			return true, nil
+		}
+		wk := openapi.NewWalker(pre, append(opts, openapi.WalkerPostVisit(post))...)
+		if err := wk.Walk(doc); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := visited, tree.pruned(nodeType); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", nodeType, got, want)
+		}
+		if got, want := len(postVisited), len(visited); got >= want {
+			t.Errorf("%v: got %v, want < %v", nodeType, got, want)
+		}
+
+		// Stop the walk at the first node of each type.
+		for _, stop := range []func() (bool, error){
+			func() (bool, error) { return true, openapi.SkipAll },
+			func() (bool, error) { return false, nil },
+		} {
+			visited = nil
+			pre = func(path []string, parent, node any) (bool, error) {
+				visited = append(visited, strings.Join(path, ":"))
+				if fmt.Sprintf("%T", node) == nodeType {
+					return stop()
+				}
+				return true, nil
+			}
+			if err := openapi.NewWalker(pre, opts...).Walk(doc); err != nil {
+				t.Fatal(err)
+			}
+			first := 0
+			for i, n := range allTypes {
+				if n == nodeType {
+					first = i
+					break
+				}
+			}
+			if got, want := visited, all[:first+1]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%v: got %v, want %v", nodeType, got, want)
+			}
+		}
+	}
+
+	errStop := errors.New("stop")
+	pre := func(path []string, parent, node any) (bool, error) {
+		return true, errStop
+	}
+	if err := openapi.NewWalker(pre).Walk(doc); !errors.Is(err, errStop) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	errFail := errors.New("fail")
+	pre := func(path []string, parent, node any) (bool, error) {
+		if _, ok := node.(*openapi3.Operation); ok && path[0] == "paths" {
+			return true, errFail
+		}
+		return true, nil
+	}
+	for _, tc := range []struct {
+		opts []openapi.WalkerOption
+		msg  string
+	}{
+		{nil, "paths:/owners:post: fail"},
+		{[]openapi.WalkerOption{openapi.WalkerJSONPointers(true)}, "/paths/~1owners/post: fail"},
+	} {
+		opts := append([]openapi.WalkerOption{openapi.WalkerOrder(openapi.SortedOrder)}, tc.opts...)
+		err := openapi.NewWalker(pre, opts...).Walk(doc)
+		var perr *openapi.PathError
+		if !errors.As(err, &perr) || !errors.Is(err, errFail) {
+			t.Fatalf("unexpected or missing error: %v", err)
+		}
+		if got, want := perr.Path, []string{"paths", "/owners", "post"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := err.Error(), tc.msg; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWalkContext(t *testing.T) {
+	doc := loadYAML("benchling.yaml")
+	type ctxKey struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	defer cancel()
+	visited := 0
+	pre := func(ctx context.Context, path []string, parent, node any) (bool, error) {
+		if got, want := ctx.Value(ctxKey{}), "value"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if visited++; visited == 10 {
+			cancel()
+		}
+		return true, nil
+	}
+	err := openapi.NewContextWalker(pre).WalkContext(ctx, doc)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if got, want := visited, 10; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalkPatterns(t *testing.T) {
+	doc := loadYAML("benchling.yaml")
+	v := &testVisitor{}
+	wk := openapi.NewWalker(v.visitor,
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerVisitPrefix("components", "schemas", "*", "properties", "webURL"))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(v.paths), 20; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, p := range v.paths {
+		parts := strings.Split(p, ":")
+		if len(parts) != 5 || parts[4] != "webURL" {
+			t.Errorf("unexpected path: %v", p)
+		}
+	}
+
+	v.paths = nil
+	wk = openapi.NewWalker(v.visitor,
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerVisitPrefix("paths", "**", "re:(get|post)", "parameters"))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.paths) == 0 {
+		t.Errorf("no paths visited")
+	}
+	for _, p := range v.paths {
+		if !strings.Contains(p, ":get:parameters") && !strings.Contains(p, ":post:parameters") {
+			t.Errorf("unexpected path: %v", p)
+		}
+	}
+
+	wk = openapi.NewWalker(v.visitor, openapi.WalkerVisitPrefix("re:("))
+	if err := wk.Walk(doc); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestWalk31(t *testing.T) {
+	doc := loadYAML("openapi31.yaml")
+	v := &testVisitor{}
+	wk := openapi.NewWalker(v.visitor, openapi.WalkerOrder(openapi.SortedOrder))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.paths, strings.Split(strings.TrimSpace(`
+info
+components
+components:schemas
+components:schemas:Pet
+components:schemas:Pet:properties
+components:schemas:Pet:properties:kind
+components:schemas:Pet:properties:kind:const
+components:schemas:Pet:properties:license
+components:schemas:Pet:properties:tags
+components:schemas:Pet:properties:tags:prefixItems
+components:schemas:Pet:properties:tags:prefixItems:0
+components:schemas:Pet:properties:tags:prefixItems:1
+components:schemas:Pet:$defs
+components:schemas:Pet:$defs:Name
+components:schemas:Pet:dependentSchemas
+components:schemas:Pet:dependentSchemas:license
+components:schemas:Pet:dependentSchemas:license:properties
+components:schemas:Pet:dependentSchemas:license:properties:owner
+components:schemas:Pet:if
+components:schemas:Pet:if:properties
+components:schemas:Pet:if:properties:kind
+components:schemas:Pet:if:properties:kind:const
+components:schemas:Pet:then
+components:schemas:Pet:else
+components:schemas:Pet:extensions
+components:schemas:Pet:extensions:x-go-name
+webhooks
+webhooks:newPet
+webhooks:newPet:post
+webhooks:newPet:post:responses
+webhooks:newPet:post:responses:200
+webhooks:newPet:post:requestBody
+webhooks:newPet:post:requestBody:content
+webhooks:newPet:post:requestBody:content:application/json
+webhooks:newPet:post:requestBody:content:application/json:schema
+externalDocs`), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Walking the document must not modify it.
+	pet := doc.Components.Schemas["Pet"].Value
+	for name, v := range map[string]any{
+		"webhooks":    doc.Extensions["webhooks"],
+		"$defs":       pet.Extensions["$defs"],
+		"if":          pet.Extensions["if"],
+		"prefixItems": pet.Properties["tags"].Value.Extensions["prefixItems"],
+	} {
+		switch v.(type) {
+		case map[string]any, []any:
+		default:
+			t.Errorf("%v: got %T", name, v)
+		}
+	}
+
+	// Changes made to 3.1 nodes must be retained by Apply.
+	err := openapi.Apply(context.Background(), doc, func(c *openapi.Cursor) (bool, error) {
+		if sref, ok := c.Node().(*openapi3.SchemaRef); ok && c.Name() == "Name" {
+			sref.Value.MinLength = 2
+		}
+		if c.Name() == "const" {
+			return true, c.Replace("animal")
+		}
+		return true, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := openapi.FormatV3(doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"minLength: 2", "const: animal"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("%s\ndoes not contain %v", out, want)
+		}
+	}
+
+	// And by other walkers once the document has been decoded.
+	if err := openapi.Decode31(context.Background(), doc); err != nil {
+		t.Fatal(err)
+	}
+	wk = openapi.NewTypedWalker(openapi.TypedVisitor{
+		Schema: func(path []string, parent any, sref *openapi3.SchemaRef) (bool, error) {
+			if path[len(path)-1] == "Name" {
+				sref.Value.MaxLength = openapi3.Uint64Ptr(32)
+			}
+			return true, nil
+		},
+		Operation: func(path []string, parent any, op *openapi3.Operation) (bool, error) {
+			op.OperationID = "newPet"
+			return true, nil
+		},
+	})
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	out, err = openapi.FormatV3(doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"maxLength: 32", "operationId: newPet"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("%s\ndoes not contain %v", out, want)
+		}
+	}
+}
+
+func TestRewriteTypeArrays(t *testing.T) {
+	data, err := openapi.RewriteTypeArrays([]byte(`openapi: 3.1.0
+info: {title: types, version: 1.0.0}
+paths: {}
+components:
+  schemas:
+    Name:
+      type: [string, "null"]
+      example: {type: [a, b]}
+    Id:
+      type: [string, integer]
+      anyOf:
+        - minimum: 1
+    Nothing:
+      type: ["null"]
+    Any:
+      type: [string, integer, "null"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	schemas := doc.Components.Schemas
+	if name := schemas["Name"].Value; name.Type != "string" || !name.Nullable {
+		t.Errorf("Name: got %v, %v", name.Type, name.Nullable)
+	}
+	if got, want := schemas["Name"].Value.Example, map[string]any{"type": []any{"a", "b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if nothing := schemas["Nothing"].Value; !nothing.Nullable || !reflect.DeepEqual(nothing.Enum, []any{nil}) {
+		t.Errorf("Nothing: got %v, %v", nothing.Nullable, nothing.Enum)
+	}
+
+	v := &testVisitor{}
+	wk := openapi.NewWalker(v.visitor, openapi.WalkerOrder(openapi.SortedOrder), openapi.WalkerVisitPrefix("components", "schemas"))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.paths, strings.Split(strings.TrimSpace(`
+components:schemas
+components:schemas:Any
+components:schemas:Any:anyOf
+components:schemas:Any:anyOf:0
+components:schemas:Any:anyOf:1
+components:schemas:Id
+components:schemas:Id:anyOf
+components:schemas:Id:anyOf:0
+components:schemas:Id:allOf
+components:schemas:Id:allOf:0
+components:schemas:Id:allOf:0:anyOf
+components:schemas:Id:allOf:0:anyOf:0
+components:schemas:Id:allOf:0:anyOf:1
+components:schemas:Name
+components:schemas:Nothing`), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for i, typ := range []string{"string", "integer"} {
+		s := schemas["Any"].Value.AnyOf[i].Value
+		if s.Type != typ || !s.Nullable {
+			t.Errorf("Any: %v: got %v, %v", i, s.Type, s.Nullable)
+		}
+	}
+}
+
+func TestRewriteTypeArraysNames(t *testing.T) {
+	// Properties, schemas and responses may be named after keywords whose
+	// values are not searched for type arrays, eg. default.
+	data, err := openapi.RewriteTypeArrays([]byte(`openapi: 3.1.0
+info: {title: types, version: 1.0.0}
+paths:
+  /pets:
+    get:
+      responses:
+        default:
+          description: pets
+          content:
+            application/json:
+              schema: {type: [string, "null"]}
+components:
+  schemas:
+    Pet:
+      type: object
+      default: {type: [a, b]}
+      properties:
+        default: {type: [string, "null"]}
+        example: {type: [integer, "null"]}
+        x-name: {type: [string, "null"]}
+    default:
+      type: [boolean, "null"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	schemas := doc.Components.Schemas
+	pet := schemas["Pet"].Value
+	for _, tc := range []struct {
+		name   string
+		schema *openapi3.Schema
+		typ    string
+	}{
+		{"properties:default", pet.Properties["default"].Value, "string"},
+		{"properties:example", pet.Properties["example"].Value, "integer"},
+		{"properties:x-name", pet.Properties["x-name"].Value, "string"},
+		{"schemas:default", schemas["default"].Value, "boolean"},
+		{"responses:default", doc.Paths["/pets"].Get.Responses.Default().Value.Content.Get("application/json").Schema.Value, "string"},
+	} {
+		if tc.schema.Type != tc.typ || !tc.schema.Nullable {
+			t.Errorf("%v: got %v, %v", tc.name, tc.schema.Type, tc.schema.Nullable)
+		}
+	}
+	if got, want := pet.Default, map[string]any{"type": []any{"a", "b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalkTrace(t *testing.T) {
+	doc := loadYAML("kitchensink.yaml")
+	v := &testVisitor{}
+	out := &strings.Builder{}
+	wk := openapi.NewWalker(v.visitor,
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerTracePaths(true),
+		openapi.WalkerTraceWriter(out))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), strings.Join(v.paths, "\n")+"\n"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	wk = openapi.NewWalker(v.visitor,
+		openapi.WalkerOrder(openapi.SortedOrder),
+		openapi.WalkerTracePaths(true),
+		openapi.WalkerTraceDetails(true),
+		openapi.WalkerJSONPointers(true),
+		openapi.WalkerTraceWriter(out))
+	if err := wk.Walk(doc); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	for _, re := range []string{
+		`^/info \*openapi3.Info \d.*s$`,
+		`^/paths/~1pets~1{id}/get/parameters/0 \*openapi3.ParameterRef \$ref=#/components/parameters/Limit \d.*s$`,
+	} {
+		found := false
+		for _, l := range lines {
+			if regexp.MustCompile(re).MatchString(l) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%v: not found in trace output", re)
+		}
+	}
+}